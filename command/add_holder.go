@@ -0,0 +1,60 @@
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kreuzwerker/yess/result"
+	"github.com/kreuzwerker/yess/split"
+	"github.com/spf13/cobra"
+)
+
+const errMissingRecipients = "please pass the new holders with --recipient"
+
+var addHolderCmd = &cobra.Command{
+
+	Use:   "add-holder",
+	Short: "Add parts for new holders to a result after combining it, without re-issuing the existing parts",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if len(conf.Recipients) == 0 {
+			return fmt.Errorf(errMissingRecipients)
+		}
+
+		res, err := result.Load(os.Stdin)
+
+		if err != nil {
+			return err
+		}
+
+		recipients, err := recipients(conf.Registry, conf.Recipients, conf.Slot)
+
+		if err != nil {
+			return err
+		}
+
+		s := split.New(backend, out, pin)
+		s.UnknownX = conf.AllowUnknownX
+
+		if err := s.AddHolders(res, recipients); err != nil {
+			return err
+		}
+
+		return res.Save(os.Stdout)
+
+	},
+}
+
+func init() {
+
+	flag(addHolderCmd.Flags(),
+		false,
+		"allow-unknown-x",
+		"",
+		"YESS_ALLOW_UNKNOWN_X",
+		"adds holders although the x-coordinates of parts of older results are unknown, which may prevent the holders of colliding shares from combining together",
+	)
+
+	rootCmd.AddCommand(addHolderCmd)
+
+}
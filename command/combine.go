@@ -1,6 +1,8 @@
 package command
 
 import (
+	"fmt"
+	"io"
 	"os"
 
 	"github.com/kreuzwerker/yess/result"
@@ -8,27 +10,87 @@ import (
 	"github.com/spf13/cobra"
 )
 
+const (
+	errMissingIn        = "the result has a streamed payload - please pass it with --in"
+	errMissingStreamOut = "the result has a streamed payload - please pass the output file with --out, which is only created once the whole payload has been verified"
+)
+
 var combineCmd = &cobra.Command{
 
 	Use:   "combine",
 	Short: "Combined and decrypt a secret using Yubikeys",
 	RunE: func(cmd *cobra.Command, args []string) error {
 
-		result, err := result.Load(os.Stdin)
+		res, err := result.Load(os.Stdin)
 
 		if err != nil {
 			return err
 		}
 
-		secret, err := split.New(out).Combine(result)
+		if err := loadPayload(res); err != nil {
+			return err
+		}
+
+		var (
+			o *output
+			s = split.New(backend, out, pin)
+			w io.Writer
+		)
+
+		if conf.Out != "" {
+
+			if o, err = create(conf.Out); err != nil {
+				return err
+			}
+
+			defer o.Discard()
+
+			w = o
+
+		}
+
+		if res.Mode == result.ModeStream {
+
+			if conf.In == "" {
+				return fmt.Errorf(errMissingIn)
+			}
+
+			// chunks are decrypted before the end of the payload has been verified, so they must not reach stdout
+			if o == nil {
+				return fmt.Errorf(errMissingStreamOut)
+			}
+
+			in, err := input()
+
+			if err != nil {
+				return err
+			}
+
+			defer in.Close()
+
+			s.Stream = &split.Stream{In: in, Out: w}
+
+		}
+
+		secret, err := s.Combine(res)
 
 		if err != nil {
 			return err
 		}
 
-		_, err = os.Stderr.Write(secret)
+		if w == nil {
+			w = os.Stderr
+		}
+
+		if _, err := w.Write(secret); err != nil {
+			return err
+		}
+
+		if o != nil {
+			return o.Commit()
+		}
 
-		return err
+		return nil
 
 	},
 }
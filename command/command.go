@@ -36,8 +36,16 @@ func flag(fs *pflag.FlagSet, def interface{}, long, short, env, desc string) {
 
 	case bool:
 		fs.BoolP(long, short, t, desc)
+	case string:
+		fs.StringP(long, short, t, desc)
+	case []string:
+		fs.StringSliceP(long, short, t, desc)
+	case int:
+		fs.IntP(long, short, t, desc)
 	case uint8:
 		fs.Uint8P(long, short, t, desc)
+	case uint32:
+		fs.Uint32P(long, short, t, desc)
 	default:
 		panic(fmt.Sprintf("unexpected default value for type %T", def))
 	}
@@ -49,6 +57,6 @@ func flag(fs *pflag.FlagSet, def interface{}, long, short, env, desc string) {
 		viper.BindEnv(long, env)
 	}
 
-	viper.SetDefault(long, fs.Lookup(long).DefValue)
+	viper.SetDefault(long, def)
 
 }
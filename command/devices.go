@@ -0,0 +1,46 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+const logNoDevices = "no devices found"
+
+var devicesCmd = &cobra.Command{
+
+	Use:   "devices",
+	Short: "List the connected devices with their reader, serial and firmware",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		infos, err := backend.Devices()
+
+		if err != nil {
+			return err
+		}
+
+		if len(infos) == 0 {
+			out(logNoDevices)
+			return nil
+		}
+
+		for _, info := range infos {
+
+			if info.Err != nil {
+				fmt.Printf("%s\terror: %s\n", info.Reader, info.Err)
+				continue
+			}
+
+			fmt.Printf("%s\tserial %d\tfirmware %s\n", info.Reader, info.Serial, info.Version)
+
+		}
+
+		return nil
+
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(devicesCmd)
+}
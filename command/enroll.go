@@ -0,0 +1,95 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/kreuzwerker/yess/device"
+	"github.com/kreuzwerker/yess/registry"
+	"github.com/spf13/cobra"
+)
+
+const (
+	errNoCertificate         = "no certificate present in slot %s"
+	errNoRegistry            = "no registry file given"
+	logConnectHolderAndEnter = "please connect the device of holder %q and enter PIN (or press enter to use the default PIN)"
+	logEnrolled              = "enrolled holder %q: serial %d, slot %s, firmware %s, subject %s, fingerprint %s"
+)
+
+var enrollCmd = &cobra.Command{
+
+	Use:   "enroll NAME",
+	Short: "Enroll the key management certificate of a holder's device into the registry",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if conf.Registry == "" {
+			return fmt.Errorf(errNoRegistry)
+		}
+
+		reg, err := registry.LoadFile(conf.Registry)
+
+		if err != nil {
+			return err
+		}
+
+		out(logConnectHolderAndEnter, args[0])
+
+		pin, err := pin()
+
+		if err != nil {
+			return err
+		}
+
+		d, err := backend.Open(pin)
+
+		if err != nil {
+			return err
+		}
+
+		defer d.Close()
+
+		cert := d.Certificate()
+
+		if cert == nil {
+			return fmt.Errorf(errNoCertificate, d.Slot())
+		}
+
+		holder := &registry.Holder{
+			Certificate: cert.Raw,
+			Name:        args[0],
+			Serial:      d.Serial(),
+			Slot:        d.Slot(),
+		}
+
+		if v, ok := d.(device.Versioner); ok {
+
+			if holder.Firmware, err = v.Version(); err != nil {
+				return err
+			}
+
+		}
+
+		if err := reg.Add(holder); err != nil {
+			return err
+		}
+
+		fingerprint, err := holder.Fingerprint()
+
+		if err != nil {
+			return err
+		}
+
+		if err := reg.SaveFile(conf.Registry); err != nil {
+			return err
+		}
+
+		out(logEnrolled, holder.Name, holder.Serial, holder.Slot, holder.Firmware, cert.Subject, fingerprint)
+
+		return nil
+
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(enrollCmd)
+}
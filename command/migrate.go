@@ -0,0 +1,71 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/kreuzwerker/yess/result"
+	"github.com/kreuzwerker/yess/split"
+	"github.com/spf13/cobra"
+)
+
+const logMigrated = "secret recovered, writing result in version %d"
+
+var migrateCmd = &cobra.Command{
+
+	Use:   "migrate",
+	Short: "Upgrade a result of an older version after verifying it through a combine",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		res, err := result.Load(os.Stdin)
+
+		if err != nil {
+			return err
+		}
+
+		if err := loadPayload(res); err != nil {
+			return err
+		}
+
+		s := split.New(backend, out, pin)
+
+		// streamed payloads are verified, but not written
+		if res.Mode == result.ModeStream {
+
+			if conf.In == "" {
+				return fmt.Errorf(errMissingIn)
+			}
+
+			in, err := input()
+
+			if err != nil {
+				return err
+			}
+
+			defer in.Close()
+
+			s.Stream = &split.Stream{In: in, Out: ioutil.Discard}
+
+		}
+
+		// only upgrade results that can still be combined
+		if err := s.Migrate(res); err != nil {
+			return err
+		}
+
+		out(logMigrated, res.Version)
+
+		// the payload file is left untouched
+		if conf.Payload != "" {
+			res.Payload = nil
+		}
+
+		return res.Save(os.Stdout)
+
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+}
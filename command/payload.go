@@ -0,0 +1,49 @@
+package command
+
+import (
+	"io/ioutil"
+
+	"github.com/kreuzwerker/yess/result"
+	"github.com/pkg/errors"
+)
+
+const (
+	errFailedToReadPayload  = "failed to read payload file %q"
+	errFailedToWritePayload = "failed to write payload file %q"
+)
+
+// loadPayload reads the payload of a result from the sidecar file, if given
+func loadPayload(res *result.Result) error {
+
+	if conf.Payload == "" || res.Mode != result.ModeDEK {
+		return nil
+	}
+
+	payload, err := ioutil.ReadFile(conf.Payload)
+
+	if err != nil {
+		return errors.Wrapf(err, errFailedToReadPayload, conf.Payload)
+	}
+
+	res.Payload = payload
+
+	return nil
+
+}
+
+// savePayload moves the payload of a result into the sidecar file, if given
+func savePayload(res *result.Result) error {
+
+	if conf.Payload == "" || res.Mode != result.ModeDEK {
+		return nil
+	}
+
+	if err := ioutil.WriteFile(conf.Payload, res.Payload, 0600); err != nil {
+		return errors.Wrapf(err, errFailedToWritePayload, conf.Payload)
+	}
+
+	res.Payload = nil
+
+	return nil
+
+}
@@ -0,0 +1,79 @@
+package command
+
+import (
+	"io/ioutil"
+
+	"github.com/kreuzwerker/yess/split"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	errFailedToParsePolicy = "failed to parse policy %q"
+	errFailedToReadPolicy  = "failed to read policy %q"
+)
+
+// policyName names the root group of policies without a name
+const policyName = "policy"
+
+// group is a group of a policy file, whose holders reference enrolled holders or certificate files like recipients
+type group struct {
+	Groups    []*group `yaml:"groups"`
+	Holders   []string `yaml:"holders"`
+	Name      string   `yaml:"name"`
+	Threshold int      `yaml:"threshold"`
+}
+
+// loadPolicy loads a policy file and resolves its holders
+func loadPolicy(path string) (*split.Policy, error) {
+
+	in, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToReadPolicy, path)
+	}
+
+	var root group
+
+	if err := yaml.UnmarshalStrict(in, &root); err != nil {
+		return nil, errors.Wrapf(err, errFailedToParsePolicy, path)
+	}
+
+	if root.Name == "" {
+		root.Name = policyName
+	}
+
+	return root.policy()
+
+}
+
+// policy resolves the holders of the group and its subgroups
+func (g *group) policy() (*split.Policy, error) {
+
+	holders, err := recipients(conf.Registry, g.Holders, conf.Slot)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := &split.Policy{
+		Holders:   holders,
+		Name:      g.Name,
+		Threshold: g.Threshold,
+	}
+
+	for _, sub := range g.Groups {
+
+		group, err := sub.policy()
+
+		if err != nil {
+			return nil, err
+		}
+
+		p.Groups = append(p.Groups, group)
+
+	}
+
+	return p, nil
+
+}
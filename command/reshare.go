@@ -0,0 +1,92 @@
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kreuzwerker/yess/result"
+	"github.com/kreuzwerker/yess/split"
+	"github.com/spf13/cobra"
+)
+
+const errMissingOut = "the result has a streamed payload - please pass the new encrypted payload with --out"
+
+var reshareCmd = &cobra.Command{
+
+	Use:   "reshare",
+	Short: "Recover a secret in memory and split it again for new holders or a new threshold",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		res, err := result.Load(os.Stdin)
+
+		if err != nil {
+			return err
+		}
+
+		if err := loadPayload(res); err != nil {
+			return err
+		}
+
+		var (
+			o *output
+			s = split.New(backend, out, pin)
+		)
+
+		s.Prime = conf.Prime
+		s.VSS = conf.VSS
+
+		if res.Mode == result.ModeStream {
+
+			if conf.In == "" {
+				return fmt.Errorf(errMissingIn)
+			}
+
+			if conf.Out == "" {
+				return fmt.Errorf(errMissingOut)
+			}
+
+			in, err := input()
+
+			if err != nil {
+				return err
+			}
+
+			defer in.Close()
+
+			if o, err = create(conf.Out); err != nil {
+				return err
+			}
+
+			defer o.Discard()
+
+			s.Stream = &split.Stream{In: in, Out: o}
+
+		}
+
+		next, err := s.Reshare(res, splitter(s))
+
+		if err != nil {
+			return err
+		}
+
+		if o != nil {
+
+			if err := o.Commit(); err != nil {
+				return err
+			}
+
+		}
+
+		// the payload stays embedded, since the payload file still belongs to the previous result
+		return next.Save(os.Stdout)
+
+	},
+}
+
+func init() {
+
+	sharing(reshareCmd.Flags())
+
+	rootCmd.AddCommand(reshareCmd)
+
+}
@@ -0,0 +1,54 @@
+package command
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kreuzwerker/yess/result"
+	"github.com/kreuzwerker/yess/split"
+	"github.com/spf13/cobra"
+)
+
+const (
+	errMissingNewHolder = "please pass exactly one new device with --recipient"
+	errMissingSerial    = "please pass the serial of the replaced device with --serial"
+)
+
+var rewrapCmd = &cobra.Command{
+
+	Use:   "rewrap",
+	Short: "Move the part of a single device to a new device or certificate, without the other holders",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		if conf.Serial == 0 {
+			return fmt.Errorf(errMissingSerial)
+		}
+
+		if len(conf.Recipients) != 1 {
+			return fmt.Errorf(errMissingNewHolder)
+		}
+
+		res, err := result.Load(os.Stdin)
+
+		if err != nil {
+			return err
+		}
+
+		recipients, err := recipients(conf.Registry, conf.Recipients, conf.Slot)
+
+		if err != nil {
+			return err
+		}
+
+		if err := split.New(backend, out, pin).Rewrap(res, conf.Serial, recipients[0]); err != nil {
+			return err
+		}
+
+		return res.Save(os.Stdout)
+
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rewrapCmd)
+}
@@ -1,20 +1,32 @@
 package command
 
 import (
+	"fmt"
 	"log"
 	"os"
 
 	"github.com/kreuzwerker/yess/config"
+	"github.com/kreuzwerker/yess/device"
 	"github.com/kreuzwerker/yess/share"
+	"github.com/kreuzwerker/yess/soft"
+	"github.com/kreuzwerker/yess/vss"
 	"github.com/kreuzwerker/yess/yubikey"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+const (
+	backendSoft    = "soft"
+	backendYubikey = "yubikey"
+)
+
+const errUnknownBackend = "unknown backend %q"
+
 var (
-	conf  config.Config
-	debug = log.New(os.Stderr, "[DEBUG] ", log.LstdFlags|log.Lshortfile)
-	info  = log.New(os.Stderr, "[INFO] ", log.LstdFlags)
+	backend device.Backend
+	conf    config.Config
+	debug   = log.New(os.Stderr, "[DEBUG] ", log.LstdFlags|log.Lshortfile)
+	info    = log.New(os.Stderr, "[INFO] ", log.LstdFlags)
 )
 
 var rootCmd = &cobra.Command{
@@ -24,15 +36,46 @@ var rootCmd = &cobra.Command{
 	Use:           app,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 
+		// commands may share flag names, so the flags of the executed command take precedence
+		if err := viper.BindPFlags(cmd.Flags()); err != nil {
+			return err
+		}
+
 		if err := viper.Unmarshal(&conf); err != nil {
 			return err
 		}
 
 		if conf.Verbose {
+			device.Debug = debug.Printf
 			share.Debug = debug.Printf
+			vss.Debug = debug.Printf
 			yubikey.Debug = debug.Printf
 		}
 
+		switch conf.Backend {
+		case backendSoft:
+
+			b, err := soft.Load(conf.SoftKeys...)
+
+			if err != nil {
+				return err
+			}
+
+			b.Hub = conf.SoftHub
+			backend = b
+
+		case backendYubikey:
+
+			backend = &yubikey.Backend{
+				Reader: conf.Reader,
+				Serial: conf.Serial,
+				Slot:   conf.Slot,
+			}
+
+		default:
+			return fmt.Errorf(errUnknownBackend, conf.Backend)
+		}
+
 		return nil
 
 	},
@@ -40,6 +83,98 @@ var rootCmd = &cobra.Command{
 
 func init() {
 
+	flag(rootCmd.PersistentFlags(),
+		backendYubikey,
+		"backend",
+		"",
+		"YESS_BACKEND",
+		"selects the device backend - use \"soft\" for software devices in tests and dry runs",
+	)
+
+	flag(rootCmd.PersistentFlags(),
+		false,
+		"soft-hub",
+		"",
+		"YESS_SOFT_HUB",
+		"connect all software devices of the soft backend simultaneously instead of inserting them one-by-one",
+	)
+
+	flag(rootCmd.PersistentFlags(),
+		[]string{},
+		"soft-key",
+		"",
+		"YESS_SOFT_KEY",
+		"key files of the software devices used by the soft backend, inserted in the given order",
+	)
+
+	flag(rootCmd.PersistentFlags(),
+		"",
+		"in",
+		"",
+		"YESS_IN",
+		"input file instead of stdin - the secret for split and the streamed payload for combine",
+	)
+
+	flag(rootCmd.PersistentFlags(),
+		"",
+		"out",
+		"",
+		"YESS_OUT",
+		"output file - the streamed payload for split (enables streaming) and the secret for combine",
+	)
+
+	flag(rootCmd.PersistentFlags(),
+		"",
+		"payload",
+		"",
+		"YESS_PAYLOAD",
+		"sidecar file for the encrypted payload of the hybrid mode - written by split, read by combine",
+	)
+
+	flag(rootCmd.PersistentFlags(),
+		"",
+		"reader",
+		"",
+		"YESS_READER",
+		"only use devices in PC/SC readers whose name contains this string - see \"devices\" for a list of readers",
+	)
+
+	flag(rootCmd.PersistentFlags(),
+		[]string{},
+		"recipient",
+		"r",
+		"YESS_RECIPIENT",
+		"encrypts one share to each given holder from the registry or PEM or DER encoded key management certificate instead of connected devices (overrides parts for split)",
+	)
+
+	flag(rootCmd.PersistentFlags(),
+		"",
+		"registry",
+		"",
+		"YESS_REGISTRY",
+		"registry file of enrolled holders, which allows to reference recipients by name",
+	)
+
+	flag(rootCmd.PersistentFlags(),
+		uint32(0),
+		"serial",
+		"",
+		"YESS_SERIAL",
+		"only use the device with this serial number",
+	)
+
+	flag(rootCmd.PersistentFlags(),
+		device.DefaultSlot,
+		"slot",
+		"",
+		"YESS_SLOT",
+		"PIV slot used for encryption - 9d (key management) or one of the retired key management slots 82 to 95",
+	)
+
+	rootCmd.PersistentFlags().MarkHidden("backend")
+	rootCmd.PersistentFlags().MarkHidden("soft-hub")
+	rootCmd.PersistentFlags().MarkHidden("soft-key")
+
 	flag(rootCmd.PersistentFlags(),
 		false,
 		"verbose",
@@ -54,3 +189,8 @@ func init() {
 func out(msg string, args ...interface{}) {
 	info.Printf(msg, args...)
 }
+
+// pin is the PIN entry function used inside the split service package
+func pin() (string, error) {
+	return yubikey.PIN(os.Stderr)
+}
@@ -1,11 +1,26 @@
 package command
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
+	"strconv"
+	"strings"
 
+	"github.com/kreuzwerker/yess/device"
+	"github.com/kreuzwerker/yess/recipient"
+	"github.com/kreuzwerker/yess/registry"
+	"github.com/kreuzwerker/yess/result"
 	"github.com/kreuzwerker/yess/split"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errInvalidBackup  = "invalid backup %q - please use <recipient or serial>=<backup recipient>"
+	errInvalidWeight  = "invalid weight %q - please use <recipient or serial>=<weight>"
+	errPolicyConflict = "--policy names the holders and cannot be combined with --recipient"
+	errStreamConflict = "--out streams the payload and cannot be combined with --dek or --payload"
 )
 
 var splitCmd = &cobra.Command{
@@ -14,18 +29,82 @@ var splitCmd = &cobra.Command{
 	Short: "Split and encrypt a secret using Yubikeys",
 	RunE: func(cmd *cobra.Command, args []string) error {
 
-		in, err := ioutil.ReadAll(os.Stdin)
+		var (
+			in     []byte
+			o      *output
+			result *result.Result
+			s      = split.New(backend, out, pin)
+		)
+
+		// a payload file implies the hybrid mode
+		s.Hybrid = conf.DEK || conf.Payload != ""
+		s.Prime = conf.Prime
+		s.VSS = conf.VSS
+
+		r, err := input()
 
 		if err != nil {
 			return err
 		}
 
-		result, err := split.New(out).Split(in, int(conf.Parts), int(conf.Threshold))
+		defer r.Close()
 
-		if err != nil {
+		if conf.Out != "" {
+
+			if s.Hybrid {
+				return fmt.Errorf(errStreamConflict)
+			}
+
+			if o, err = create(conf.Out); err != nil {
+				return err
+			}
+
+			defer o.Discard()
+
+			s.Stream = &split.Stream{In: r, Out: o}
+
+		} else if in, err = ioutil.ReadAll(r); err != nil {
+			return err
+		}
+
+		if conf.Policy != "" {
+
+			if len(conf.Recipients) > 0 {
+				return fmt.Errorf(errPolicyConflict)
+			}
+
+			policy, err := loadPolicy(conf.Policy)
+
+			if err != nil {
+				return err
+			}
+
+			if s.Backups, err = backups(conf.Backups, nil, nil); err != nil {
+				return err
+			}
+
+			result, err = s.SplitPolicy(in, policy)
+
+			if err != nil {
+				return err
+			}
+
+		} else if result, err = splitter(s)(in); err != nil {
+			return err
+		}
+
+		if err := savePayload(result); err != nil {
 			return err
 		}
 
+		if o != nil {
+
+			if err := o.Commit(); err != nil {
+				return err
+			}
+
+		}
+
 		return result.Save(os.Stdout)
 
 	},
@@ -34,21 +113,259 @@ var splitCmd = &cobra.Command{
 func init() {
 
 	flag(splitCmd.Flags(),
-		uint8(3),
+		false,
+		"dek",
+		"",
+		"YESS_DEK",
+		"encrypts the secret with a random data encryption key (DEK) and only splits the DEK, which keeps the parts small for large secrets",
+	)
+
+	flag(splitCmd.Flags(),
+		"",
+		"policy",
+		"",
+		"YESS_POLICY",
+		"splits the secret for a YAML policy of nested groups with their own thresholds and holders instead of a flat threshold (overrides parts and threshold)",
+	)
+
+	sharing(splitCmd.Flags())
+
+	rootCmd.AddCommand(splitCmd)
+
+}
+
+// sharing adds the flags of the holders, weights, backups and sharing scheme of a split
+func sharing(fs *pflag.FlagSet) {
+
+	flag(fs,
+		[]string{},
+		"backup",
+		"b",
+		"YESS_BACKUP",
+		"encrypts the part of a recipient or the device with the given serial to a backup device of the same holder as well, e.g. alice=alice-backup.pem - either device counts as the same holder",
+	)
+
+	flag(fs,
+		3,
 		"parts",
 		"p",
 		"YESS_PARTS",
-		"specifies the number of shares generated",
+		"specifies the number of shares generated (up to 255 unless --prime or --vss is used)",
 	)
 
-	flag(splitCmd.Flags(),
-		uint8(2),
+	flag(fs,
+		false,
+		"prime",
+		"",
+		"YESS_PRIME",
+		"uses Shamir secret sharing over a 256 bit prime field instead of GF(256), which allows more than 255 parts",
+	)
+
+	flag(fs,
+		2,
 		"threshold",
 		"t",
 		"YESS_THRESHOLD",
 		"specifies number of shares required for reconstruction",
 	)
 
-	rootCmd.AddCommand(splitCmd)
+	flag(fs,
+		false,
+		"vss",
+		"",
+		"YESS_VSS",
+		"splits a random DEK with Feldman verifiable secret sharing and stores commitments in the result, which allows each holder to verify their share with verify-share (implies --dek unless streaming)",
+	)
+
+	flag(fs,
+		[]string{},
+		"weight",
+		"w",
+		"YESS_WEIGHT",
+		"assigns a recipient or the device with the given serial several shares in a single part, e.g. alice=2 - the threshold counts shares",
+	)
+
+}
+
+// splitter returns a function which splits secrets for the configured recipients, or the connected devices if there are none, with the configured weights and backups
+func splitter(s *split.Split) func([]byte) (*result.Result, error) {
+
+	return func(secret []byte) (*result.Result, error) {
+
+		if len(conf.Recipients) == 0 {
+
+			var err error
+
+			if s.Backups, err = backups(conf.Backups, nil, nil); err != nil {
+				return nil, err
+			}
+
+			if s.Weights, err = weights(conf.Weights, nil, nil); err != nil {
+				return nil, err
+			}
+
+			return s.Split(secret, conf.Parts, conf.Threshold)
+
+		}
+
+		recipients, err := recipients(conf.Registry, conf.Recipients, conf.Slot)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if s.Backups, err = backups(conf.Backups, conf.Recipients, recipients); err != nil {
+			return nil, err
+		}
+
+		if s.Weights, err = weights(conf.Weights, conf.Recipients, recipients); err != nil {
+			return nil, err
+		}
+
+		return s.SplitTo(secret, recipients, conf.Threshold)
+
+	}
+
+}
+
+// recipients resolves the given names of holders in the registry or certificate files of the given slot
+func recipients(path string, names []string, slot string) ([]device.Recipient, error) {
+
+	reg := new(registry.Registry)
+
+	if path != "" {
+
+		var err error
+
+		if reg, err = registry.LoadFile(path); err != nil {
+			return nil, err
+		}
+
+	}
+
+	var recipients []device.Recipient
+
+	for _, name := range names {
+
+		if holder, err := reg.Find(name); err == nil {
+
+			r, err := holder.Recipient()
+
+			if err != nil {
+				return nil, err
+			}
+
+			recipients = append(recipients, r)
+
+			continue
+
+		}
+
+		r, err := recipient.Load(name, slot)
+
+		if err != nil {
+			return nil, err
+		}
+
+		recipients = append(recipients, r)
+
+	}
+
+	return recipients, nil
+
+}
+
+// backups resolves backups of the form <recipient or serial>=<backup recipient> to the serials of the resolved recipients with the given names or the given serials
+func backups(specs []string, names []string, resolved []device.Recipient) (map[uint32][]device.Recipient, error) {
+
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	backups := make(map[uint32][]device.Recipient)
+
+	for _, spec := range specs {
+
+		idx := strings.Index(spec, "=")
+
+		if idx < 0 {
+			return nil, fmt.Errorf(errInvalidBackup, spec)
+		}
+
+		serial, err := holder(spec[:idx], names, resolved)
+
+		if err != nil {
+			return nil, fmt.Errorf(errInvalidBackup, spec)
+		}
+
+		backup, err := recipients(conf.Registry, []string{spec[idx+1:]}, conf.Slot)
+
+		if err != nil {
+			return nil, err
+		}
+
+		backups[serial] = append(backups[serial], backup...)
+
+	}
+
+	return backups, nil
+
+}
+
+// holder resolves the name of a recipient or a serial to a serial
+func holder(name string, names []string, resolved []device.Recipient) (uint32, error) {
+
+	for idx, n := range names {
+
+		if n == name {
+			return resolved[idx].Serial(), nil
+		}
+
+	}
+
+	serial, err := strconv.ParseUint(name, 10, 32)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return uint32(serial), nil
+
+}
+
+// weights resolves weights of the form <recipient or serial>=<weight> to the serials of the resolved recipients with the given names or the given serials
+func weights(specs []string, names []string, resolved []device.Recipient) (map[uint32]int, error) {
+
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	weights := make(map[uint32]int)
+
+	for _, spec := range specs {
+
+		idx := strings.LastIndex(spec, "=")
+
+		if idx < 0 {
+			return nil, fmt.Errorf(errInvalidWeight, spec)
+		}
+
+		weight, err := strconv.Atoi(spec[idx+1:])
+
+		if err != nil {
+			return nil, fmt.Errorf(errInvalidWeight, spec)
+		}
+
+		serial, err := holder(spec[:idx], names, resolved)
+
+		if err != nil {
+			return nil, fmt.Errorf(errInvalidWeight, spec)
+		}
+
+		weights[serial] = weight
+
+	}
+
+	return weights, nil
 
 }
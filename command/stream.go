@@ -0,0 +1,75 @@
+package command
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errFailedToOpenInput   = "failed to open input file %q"
+	errFailedToWriteOutput = "failed to write output file %q"
+)
+
+// output is a temporary file that only replaces its destination once committed, so that failures never leave partial output behind
+type output struct {
+	*os.File
+	path string
+}
+
+// create creates a temporary output file next to the given path
+func create(path string) (*output, error) {
+
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToWriteOutput, path)
+	}
+
+	return &output{
+		File: f,
+		path: path,
+	}, nil
+
+}
+
+// Commit closes the temporary file and moves it to its destination
+func (o *output) Commit() error {
+
+	if err := o.Close(); err != nil {
+		return errors.Wrapf(err, errFailedToWriteOutput, o.path)
+	}
+
+	if err := os.Rename(o.Name(), o.path); err != nil {
+		return errors.Wrapf(err, errFailedToWriteOutput, o.path)
+	}
+
+	return nil
+
+}
+
+// Discard closes and removes the temporary file, unless it has been committed
+func (o *output) Discard() {
+	o.Close()
+	os.Remove(o.Name())
+}
+
+// input opens the input file or, if none is given, stdin
+func input() (io.ReadCloser, error) {
+
+	if conf.In == "" {
+		return os.Stdin, nil
+	}
+
+	f, err := os.Open(conf.In)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToOpenInput, conf.In)
+	}
+
+	return f, nil
+
+}
@@ -0,0 +1,68 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kreuzwerker/yess/result"
+	"github.com/kreuzwerker/yess/split"
+	"github.com/spf13/cobra"
+)
+
+const logVerified = "verified result %s with the devices %v in %s"
+
+var verifyCmd = &cobra.Command{
+
+	Use:   "verify",
+	Short: "Verify that a quorum can recover a secret without revealing it",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		res, err := result.Load(os.Stdin)
+
+		if err != nil {
+			return err
+		}
+
+		if err := loadPayload(res); err != nil {
+			return err
+		}
+
+		s := split.New(backend, out, pin)
+
+		if res.Mode == result.ModeStream {
+
+			if conf.In == "" {
+				return fmt.Errorf(errMissingIn)
+			}
+
+			in, err := input()
+
+			if err != nil {
+				return err
+			}
+
+			defer in.Close()
+
+			s.Stream = &split.Stream{In: in}
+
+		}
+
+		start := time.Now()
+
+		serials, err := s.Verify(res)
+
+		if err != nil {
+			return err
+		}
+
+		out(logVerified, res.ID, serials, time.Since(start).Round(time.Millisecond))
+
+		return nil
+
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
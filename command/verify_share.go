@@ -0,0 +1,32 @@
+package command
+
+import (
+	"os"
+
+	"github.com/kreuzwerker/yess/result"
+	"github.com/kreuzwerker/yess/split"
+	"github.com/spf13/cobra"
+)
+
+var verifyShareCmd = &cobra.Command{
+
+	Use:   "verify-share",
+	Short: "Verify the share of a single device against the commitments of a result without recovering the secret",
+	RunE: func(cmd *cobra.Command, args []string) error {
+
+		res, err := result.Load(os.Stdin)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = split.New(backend, out, pin).VerifyShare(res)
+
+		return err
+
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyShareCmd)
+}
@@ -1,7 +1,25 @@
 package config
 
 type Config struct {
-	Parts     uint8 `mapstructure:"parts"`
-	Threshold uint8 `mapstructure:"threshold"`
-	Verbose   bool  `mapstructure:"verbose"`
+	AllowUnknownX bool     `mapstructure:"allow-unknown-x"`
+	Backend       string   `mapstructure:"backend"`
+	Backups       []string `mapstructure:"backup"`
+	DEK           bool     `mapstructure:"dek"`
+	In            string   `mapstructure:"in"`
+	Out           string   `mapstructure:"out"`
+	Parts         int      `mapstructure:"parts"`
+	Payload       string   `mapstructure:"payload"`
+	Policy        string   `mapstructure:"policy"`
+	Prime         bool     `mapstructure:"prime"`
+	Reader        string   `mapstructure:"reader"`
+	Recipients    []string `mapstructure:"recipient"`
+	Registry      string   `mapstructure:"registry"`
+	Serial        uint32   `mapstructure:"serial"`
+	Slot          string   `mapstructure:"slot"`
+	SoftHub       bool     `mapstructure:"soft-hub"`
+	SoftKeys      []string `mapstructure:"soft-key"`
+	Threshold     int      `mapstructure:"threshold"`
+	Verbose       bool     `mapstructure:"verbose"`
+	VSS           bool     `mapstructure:"vss"`
+	Weights       []string `mapstructure:"weight"`
 }
@@ -0,0 +1,44 @@
+// Package device defines the interfaces that split and combine use to encrypt shares to and decrypt shares from devices
+package device
+
+import (
+	"crypto/x509"
+
+	"github.com/kreuzwerker/yess/result"
+)
+
+// Recipient represents the public part of a device, which is sufficient to encrypt shares
+type Recipient interface {
+	Certificate() *x509.Certificate                                          // Certificate returns the certificate of the selected slot
+	Encrypt(res *result.Result, index int, shp []byte) (*result.Part, error) // Encrypt encrypts the given plaintext share of the result into the part with the given index
+	Serial() uint32                                                          // Serial returns the devices serial number
+	Slot() string                                                            // Slot returns the hex name of the selected slot, e.g. 9d
+	Vendor() string                                                          // Vendor returns the devices vendor string
+}
+
+// Device represents a connected device, which is capable of decrypting shares encrypted to it
+type Device interface {
+	Recipient
+	Close() error                                               // Close closes the connection to the device
+	Decrypt(res *result.Result, p *result.Part) ([]byte, error) // Decrypt decrypts the given part of the result, yielding the plaintext share
+}
+
+// Versioner is implemented by devices that report their firmware version
+type Versioner interface {
+	Version() (string, error) // Version returns the firmware version
+}
+
+// Info describes a connected device as far as it can be determined without logging in
+type Info struct {
+	Err     error  // Err is set if the device could not be queried, e.g. because the reader has no PIV compatible card
+	Reader  string // Reader is the name of the PC/SC reader
+	Serial  uint32 // Serial is the devices serial number
+	Version string // Version is the firmware version
+}
+
+// Backend connects to devices
+type Backend interface {
+	Connect(info *Info, pin string) (Device, error) // Connect connects to the listed device and logs in with the given PIN
+	Devices() ([]*Info, error)                      // Devices lists the connected devices
+	Open(pin string) (Device, error)                // Open connects to a device and logs in with the given PIN
+}
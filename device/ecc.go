@@ -0,0 +1,101 @@
+package device
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	"github.com/kreuzwerker/yess/result"
+	"github.com/pkg/errors"
+)
+
+// decryptECC decrypts a given part using ECC keys, yielding the plaintext share
+func decryptECC(d crypto.Decrypter, ekp *ecdsa.PublicKey, res *result.Result, p *result.Part) ([]byte, error) {
+
+	// marshal the public key into the expected ANSI X9.62 format - see https://pkg.go.dev/pault.ag/go/ykpiv?tab=doc#Slot.Decrypt
+	octet := elliptic.Marshal(ekp.Curve, ekp.X, ekp.Y)
+
+	// decrypt, yielding the shared ephemeral key
+	sk, err := d.Decrypt(nil, octet, nil)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToDecryptOnDevice)
+	}
+
+	if len(sk) > size(ekp.Curve) {
+		return nil, fmt.Errorf(errInvalidSharedSecret, len(sk), size(ekp.Curve))
+	}
+
+	// devices return the fixed-length x-coordinate while version 1 encryption used its minimal encoding
+	if p.Algorithm.KDF == result.KDFSHA3 {
+		sk = new(big.Int).SetBytes(sk).Bytes()
+	} else {
+		sk = new(big.Int).SetBytes(sk).FillBytes(make([]byte, size(ekp.Curve)))
+	}
+
+	if Debug != nil {
+		Debug("decrypting with ECC using SK %x", sk)
+	}
+
+	// decrypt the ciphertext share with the shared ephemeral key
+	return open(res, p, sk)
+
+}
+
+// encryptECC encrypts the given share using ECC keys into a Result
+func encryptECC(r Recipient, dkp *ecdsa.PublicKey, res *result.Result, index int, msg []byte) (*result.Part, error) {
+
+	var (
+		ekp *ecdsa.PublicKey
+		sk  []byte
+	)
+
+	{
+
+		// generate ephemeral keypair
+		eks, px, py, err := elliptic.GenerateKey(dkp.Curve, rand.Reader)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, errFailedToGenerateEphemeralECCKeypair)
+		}
+
+		// the ecsda structure is used due to native marshalling capabilities in package X509
+		ekp = &ecdsa.PublicKey{
+			X:     px,
+			Y:     py,
+			Curve: dkp.Curve,
+		}
+
+		// perform key exchange, encoding the x-coordinate with a fixed length like devices do
+		x, _ := dkp.Curve.ScalarMult(dkp.X, dkp.Y, eks)
+		sk = x.FillBytes(make([]byte, size(dkp.Curve)))
+
+	}
+
+	if Debug != nil {
+		Debug("encrypting with ECC using SK %x", sk)
+	}
+
+	p := part(r, index)
+	p.Algorithm = result.NewAlgorithm(result.KeyAgreementECDH)
+
+	if err := p.AddKey(ekp); err != nil {
+		return nil, err
+	}
+
+	// encrypt the plaintext share with the shared ephemeral key
+	if err := seal(res, p, sk, msg); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+
+}
+
+// size returns the length of the fixed-length encoding of a coordinate on the given curve
+func size(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) / 8
+}
@@ -0,0 +1,38 @@
+package device
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/kreuzwerker/yess/result"
+	"github.com/stretchr/testify/assert"
+)
+
+type decrypter struct {
+	*ecdsa.PrivateKey
+	out []byte
+}
+
+func (d decrypter) Decrypt(_ io.Reader, _ []byte, _ crypto.DecrypterOpts) ([]byte, error) {
+	return d.out, nil
+}
+
+func TestDecryptECCInvalidSharedSecret(t *testing.T) {
+
+	assert := assert.New(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(err)
+
+	d := decrypter{PrivateKey: key, out: make([]byte, 33)}
+	p := &result.Part{Algorithm: result.NewAlgorithm(result.KeyAgreementECDH)}
+
+	_, err = decryptECC(d, &key.PublicKey, &result.Result{}, p)
+
+	assert.EqualError(err, "device returned a shared secret of 33 bytes - expected at most 32")
+
+}
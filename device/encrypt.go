@@ -0,0 +1,120 @@
+package device
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/kreuzwerker/yess/result"
+)
+
+const (
+	errFailedToDecryptOnDevice             = "failed to decrypt on device"
+	errFailedToDecryptShare                = "failed to decrypt share"
+	errFailedToGenerateEphemeralECCKeypair = "failed to generate ephemeral keypair"
+	errInvalidSharedSecret                 = "device returned a shared secret of %d bytes - expected at most %d"
+	errUnknownCipher                       = "unknown cipher %q"
+	errUnknownKeyAgreement                 = "unknown key agreement %q"
+	errUnknownPublicKeyType                = "unknown public key type %T"
+)
+
+var Debug func(string, ...interface{})
+
+// Decrypt decrypts a given part with the private key of a device, yielding the plaintext share
+func Decrypt(d crypto.Decrypter, res *result.Result, p *result.Part) ([]byte, error) {
+
+	if p.Algorithm.KeyAgreement == result.KeyAgreementRSA {
+		return decryptRSA(d, res, p)
+	}
+
+	pk, err := p.Key()
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.Algorithm.KeyAgreement {
+	case result.KeyAgreementECDH:
+
+		if t, ok := pk.(*ecdsa.PublicKey); ok {
+			return decryptECC(d, t, res, p)
+		}
+
+	case result.KeyAgreementX25519:
+
+		if t, ok := pk.(*ecdh.PublicKey); ok && t.Curve() == ecdh.X25519() {
+			return decryptX25519(d, t, res, p)
+		}
+
+	default:
+		return nil, fmt.Errorf(errUnknownKeyAgreement, p.Algorithm.KeyAgreement)
+	}
+
+	return nil, fmt.Errorf(errUnknownPublicKeyType, pk)
+
+}
+
+// Encrypt encrypts the given share of a result into the part with the given index to the public key of a recipient
+func Encrypt(r Recipient, res *result.Result, index int, msg []byte) (*result.Part, error) {
+
+	if Debug != nil {
+		Debug("encrypting plaintext share %x", msg)
+	}
+
+	pk, err := PublicKey(r.Certificate())
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := pk.(type) {
+	case *ecdsa.PublicKey:
+		return encryptECC(r, t, res, index, msg)
+	case *rsa.PublicKey:
+		return encryptRSA(r, t, res, index, msg)
+	case *ecdh.PublicKey:
+
+		if t.Curve() != ecdh.X25519() {
+			return nil, fmt.Errorf(errUnknownPublicKeyType, t)
+		}
+
+		return encryptX25519(r, t, res, index, msg)
+
+	default:
+		return nil, fmt.Errorf(errUnknownPublicKeyType, t)
+	}
+
+}
+
+// PublicKey returns the public key of a certificate - since the x509 package does not parse X25519 keys in certificates, these are parsed from the raw subject public key info
+func PublicKey(cert *x509.Certificate) (interface{}, error) {
+
+	if cert.PublicKey != nil {
+		return cert.PublicKey, nil
+	}
+
+	return x509.ParsePKIXPublicKey(cert.RawSubjectPublicKeyInfo)
+
+}
+
+// part returns a part with the given index carrying the informational fields of the recipient
+func part(r Recipient, index int) *result.Part {
+
+	cert := r.Certificate()
+
+	return &result.Part{
+		Device:    r.Vendor(),
+		Expiry:    cert.NotAfter.Format(time.RFC3339),
+		Index:     index,
+		Issuer:    cert.Issuer.String(),
+		Recipient: cert.RawSubjectPublicKeyInfo,
+		Serial:    r.Serial(),
+		Slot:      r.Slot(),
+		Subject:   cert.Subject.String(),
+	}
+
+}
@@ -0,0 +1,30 @@
+package device
+
+import "fmt"
+
+const (
+	errPINLocked = "PIN of device %d is locked"
+	errWrongPIN  = "wrong PIN for device %d (%d retries remaining)"
+)
+
+// PINError is returned when logging into a device fails because of a wrong or locked PIN
+type PINError struct {
+	Retries int    // Retries is the number of remaining PIN retries
+	Serial  uint32 // Serial is the devices serial number
+}
+
+// Error returns the error message, including the remaining retries
+func (e *PINError) Error() string {
+
+	if e.Locked() {
+		return fmt.Sprintf(errPINLocked, e.Serial)
+	}
+
+	return fmt.Sprintf(errWrongPIN, e.Serial, e.Retries)
+
+}
+
+// Locked returns true if no retries remain
+func (e *PINError) Locked() bool {
+	return e.Retries <= 0
+}
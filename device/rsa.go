@@ -0,0 +1,70 @@
+package device
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+
+	"github.com/kreuzwerker/yess/result"
+	"github.com/pkg/errors"
+)
+
+const (
+	errFailedToGenerateKey = "failed to generate key"
+	errFailedToWrapKey     = "failed to encrypt key with RSA"
+)
+
+// keySize is the size of the random key encrypting the share
+const keySize = 32
+
+// decryptRSA decrypts a given part using RSA keys, yielding the plaintext share
+func decryptRSA(d crypto.Decrypter, res *result.Result, p *result.Part) ([]byte, error) {
+
+	// decrypt, yielding the key - the PKCS #1 v1.5 padding is removed by the device (or the PIV library)
+	key, err := d.Decrypt(nil, p.Wrapped, nil)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToDecryptOnDevice)
+	}
+
+	if Debug != nil {
+		Debug("decrypting with RSA using key %x", key)
+	}
+
+	// decrypt the ciphertext share with the key
+	return open(res, p, key)
+
+}
+
+// encryptRSA encrypts the given share using RSA keys into a Result
+func encryptRSA(r Recipient, dkp *rsa.PublicKey, res *result.Result, index int, msg []byte) (*result.Part, error) {
+
+	key := make([]byte, keySize)
+
+	if _, err := rand.Read(key); err != nil {
+		return nil, errors.Wrapf(err, errFailedToGenerateKey)
+	}
+
+	// PIV devices only support raw RSA, so PKCS #1 v1.5 is the only padding the PIV library removes after decryption
+	wrapped, err := rsa.EncryptPKCS1v15(rand.Reader, dkp, key)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToWrapKey)
+	}
+
+	if Debug != nil {
+		Debug("encrypting with RSA using key %x", key)
+	}
+
+	p := part(r, index)
+	p.Algorithm = result.NewAlgorithm(result.KeyAgreementRSA)
+	p.Wrapped = wrapped
+
+	// encrypt the plaintext share with the key
+	if err := seal(res, p, key, msg); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+
+}
@@ -0,0 +1,140 @@
+package device
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/kreuzwerker/yess/encrypt"
+	"github.com/kreuzwerker/yess/result"
+)
+
+const (
+	errFailedToAuthenticateShare = "failed to authenticate share - wrong key or tampered result"
+	errUnknownKDF                = "unknown KDF %q"
+)
+
+const (
+	// label separates the keys derived by yess from keys derived for other purposes
+	label = "yess v2"
+	// labelAD separates the associated data of shares from other data
+	labelAD = "yess v2 share"
+)
+
+// open decrypts the share of a part with the key derived from the shared secret according to the parts algorithm
+func open(res *result.Result, p *result.Part, sk []byte) ([]byte, error) {
+
+	key, err := kdf(res, p, sk)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var share []byte
+
+	switch p.Algorithm.Cipher {
+	case result.CipherChaCha20Poly1305:
+
+		var ok bool
+
+		if share, ok = encrypt.OpenAEAD(key, p.Share, associated(res, p)); !ok {
+			return nil, fmt.Errorf(errFailedToAuthenticateShare)
+		}
+
+	case result.CipherSecretbox:
+
+		var ok bool
+
+		if share, ok = encrypt.Open(key, p.Share); !ok {
+			return nil, fmt.Errorf(errFailedToDecryptShare)
+		}
+
+	default:
+		return nil, fmt.Errorf(errUnknownCipher, p.Algorithm.Cipher)
+	}
+
+	if Debug != nil {
+		Debug("decrypted share %x", share)
+	}
+
+	return share, nil
+
+}
+
+// seal encrypts the share of a part with the key derived from the shared secret according to the parts algorithm
+func seal(res *result.Result, p *result.Part, sk, msg []byte) error {
+
+	key, err := kdf(res, p, sk)
+
+	if err != nil {
+		return err
+	}
+
+	switch p.Algorithm.Cipher {
+	case result.CipherChaCha20Poly1305:
+
+		if p.Share, err = encrypt.SealAEAD(key, msg, associated(res, p)); err != nil {
+			return err
+		}
+
+	case result.CipherSecretbox:
+		p.Share = encrypt.Seal(key, msg)
+	default:
+		return fmt.Errorf(errUnknownCipher, p.Algorithm.Cipher)
+	}
+
+	if Debug != nil {
+		Debug("encrypted share %x", p.Share)
+	}
+
+	return nil
+
+}
+
+// associated returns the associated data of a part, which binds its share to the result, its sharing scheme and to the recipient
+func associated(res *result.Result, p *result.Part) []byte {
+
+	var threshold, index, serial [4]byte
+
+	binary.BigEndian.PutUint32(threshold[:], uint32(res.Threshold))
+	binary.BigEndian.PutUint32(index[:], uint32(p.Index))
+	binary.BigEndian.PutUint32(serial[:], p.Serial)
+
+	return encrypt.Concat(
+		[]byte(labelAD),
+		[]byte(res.ID),
+		threshold[:],
+		index[:],
+		serial[:],
+		p.Recipient,
+		[]byte(p.Issuer),
+		[]byte(p.Subject),
+		[]byte(p.Expiry),
+		[]byte(res.Mode),
+		[]byte(res.Sharing),
+		encrypt.Concat(res.Commitments...),
+	)
+
+}
+
+// kdf derives the key of a part from the shared secret according to the parts algorithm
+func kdf(res *result.Result, p *result.Part, sk []byte) ([]byte, error) {
+
+	switch p.Algorithm.KDF {
+	case result.KDFHKDF:
+
+		// bind the ephemeral key (or the wrapped key for RSA), the recipient key and the result ID
+		ephemeral := p.PublicKey
+
+		if p.Algorithm.KeyAgreement == result.KeyAgreementRSA {
+			ephemeral = p.Wrapped
+		}
+
+		return encrypt.Derive(sk, []byte(label), ephemeral, p.Recipient, []byte(res.ID)), nil
+
+	case result.KDFSHA3:
+		return encrypt.DeriveSHA3(sk), nil
+	default:
+		return nil, fmt.Errorf(errUnknownKDF, p.Algorithm.KDF)
+	}
+
+}
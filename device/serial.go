@@ -0,0 +1,19 @@
+package device
+
+import (
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/binary"
+)
+
+// OIDSerial is the extension Yubico uses to record the device serial in attestation certificates
+var OIDSerial = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 41482, 3, 7}
+
+// DeriveSerial derives a serial number from the PKIX (DER) representation of a public key for devices that do not report one (e.g. non-Yubico PIV smartcards)
+func DeriveSerial(spki []byte) uint32 {
+
+	sum := sha256.Sum256(spki)
+
+	return binary.BigEndian.Uint32(sum[:])
+
+}
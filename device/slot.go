@@ -0,0 +1,32 @@
+package device
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const errInvalidSlot = "invalid slot %q - expected 9d or one of the retired key management slots 82 to 95"
+
+const (
+	// DefaultSlot is the PIV key management slot
+	DefaultSlot = "9d"
+	// retiredFirst and retiredLast are the key references of the retired key management slots
+	retiredFirst, retiredLast = 0x82, 0x95
+)
+
+// ParseSlot parses the hex name of a key management slot (9d) or one of the retired key management slots (82 to 95), yielding its key reference - an empty name refers to DefaultSlot
+func ParseSlot(name string) (byte, error) {
+
+	if name == "" {
+		name = DefaultSlot
+	}
+
+	key, err := strconv.ParseUint(name, 16, 8)
+
+	if err != nil || (key != 0x9d && (key < retiredFirst || key > retiredLast)) {
+		return 0, fmt.Errorf(errInvalidSlot, name)
+	}
+
+	return byte(key), nil
+
+}
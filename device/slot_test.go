@@ -0,0 +1,36 @@
+package device
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSlot(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for name, key := range map[string]byte{
+		"":   0x9d,
+		"9d": 0x9d,
+		"9D": 0x9d,
+		"82": 0x82,
+		"95": 0x95,
+	} {
+
+		out, err := ParseSlot(name)
+
+		assert.NoError(err)
+		assert.Equal(key, out)
+
+	}
+
+	for _, name := range []string{"9a", "81", "96", "x"} {
+
+		_, err := ParseSlot(name)
+
+		assert.Error(err)
+
+	}
+
+}
@@ -0,0 +1,68 @@
+package device
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/rand"
+
+	"github.com/kreuzwerker/yess/result"
+	"github.com/pkg/errors"
+)
+
+const errFailedToPerformKeyExchange = "failed to perform key exchange"
+
+// decryptX25519 decrypts a given part using X25519 keys, yielding the plaintext share
+func decryptX25519(d crypto.Decrypter, ekp *ecdh.PublicKey, res *result.Result, p *result.Part) ([]byte, error) {
+
+	// decrypt the raw public key, yielding the shared ephemeral key
+	sk, err := d.Decrypt(nil, ekp.Bytes(), nil)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToDecryptOnDevice)
+	}
+
+	if Debug != nil {
+		Debug("decrypting with X25519 using SK %x", sk)
+	}
+
+	// decrypt the ciphertext share with the shared ephemeral key
+	return open(res, p, sk)
+
+}
+
+// encryptX25519 encrypts the given share using X25519 keys into a Result
+func encryptX25519(r Recipient, dkp *ecdh.PublicKey, res *result.Result, index int, msg []byte) (*result.Part, error) {
+
+	// generate ephemeral keypair
+	eks, err := ecdh.X25519().GenerateKey(rand.Reader)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToGenerateEphemeralECCKeypair)
+	}
+
+	// perform key exchange
+	sk, err := eks.ECDH(dkp)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToPerformKeyExchange)
+	}
+
+	if Debug != nil {
+		Debug("encrypting with X25519 using SK %x", sk)
+	}
+
+	p := part(r, index)
+	p.Algorithm = result.NewAlgorithm(result.KeyAgreementX25519)
+
+	if err := p.AddKey(eks.PublicKey()); err != nil {
+		return nil, err
+	}
+
+	// encrypt the plaintext share with the shared ephemeral key
+	if err := seal(res, p, sk, msg); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+
+}
@@ -2,36 +2,127 @@
 package encrypt
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
 	nacl "golang.org/x/crypto/nacl/secretbox"
 	"golang.org/x/crypto/sha3"
 )
 
+// KeySize is the size of keys derived by Derive
+const KeySize = 32
+
+// Concat returns the concatenation of the given values, each prefixed with its length as 32 bit big-endian integer, so that no two lists of values share an encoding
+func Concat(values ...[]byte) []byte {
+
+	var (
+		length [4]byte
+		out    []byte
+	)
+
+	for _, value := range values {
+		binary.BigEndian.PutUint32(length[:], uint32(len(value)))
+		out = append(append(out, length[:]...), value...)
+	}
+
+	return out
+
+}
+
 // Decrypt decrypts a msg with secretbox, using SHA3-256 as key derivation function and a zero-nonce
 func Decrypt(sk, shpe []byte) ([]byte, bool) {
 
+	return Open(DeriveSHA3(sk), shpe)
+
+}
+
+// Derive derives a key from a shared secret with HKDF-SHA256, using the length-prefixed concatenation of the given values as info
+func Derive(sk []byte, info ...[]byte) []byte {
+
+	key := make([]byte, KeySize)
+
+	// reading less than 255 blocks from HKDF never fails
+	io.ReadFull(hkdf.New(sha256.New, sk, nil, Concat(info...)), key)
+
+	return key
+
+}
+
+// DeriveSHA3 derives a key from a shared secret as its SHA3-256 digest
+func DeriveSHA3(sk []byte) []byte {
+
+	dk := sha3.Sum256(sk)
+
+	return dk[:]
+
+}
+
+// Encrypt encrypts a msg with secretbox, using SHA3-256 as key derivation function and a zero-nonce
+func Encrypt(sk, shp []byte) []byte {
+
+	return Seal(DeriveSHA3(sk), shp)
+
+}
+
+// Open decrypts a msg with secretbox, using the given key and a zero-nonce
+func Open(dk, shpe []byte) ([]byte, bool) {
+
 	var (
-		dk    = sha3.Sum256(sk)
 		key   [32]byte
 		nonce [24]byte
 	)
 
-	copy(key[:], dk[:])
+	copy(key[:], dk)
 
 	return nacl.Open(nil, shpe, &nonce, &key)
 
 }
 
-// Encrypt encrypts a msg with secretbox, using SHA3-256 as key derivation function and a zero-nonce
-func Encrypt(sk, shp []byte) []byte {
+// OpenAEAD decrypts a msg with ChaCha20-Poly1305, using the given key, a zero-nonce and the given associated data
+func OpenAEAD(dk, shpe, ad []byte) ([]byte, bool) {
+
+	aead, err := chacha20poly1305.New(dk)
+
+	if err != nil {
+		return nil, false
+	}
+
+	var nonce [chacha20poly1305.NonceSize]byte
+
+	shp, err := aead.Open(nil, nonce[:], shpe, ad)
+
+	return shp, err == nil
+
+}
+
+// Seal encrypts a msg with secretbox, using the given key and a zero-nonce - every key must only be used once
+func Seal(dk, shp []byte) []byte {
 
 	var (
-		dk    = sha3.Sum256(sk)
 		key   [32]byte
 		nonce [24]byte // zero
 	)
 
-	copy(key[:], dk[:])
+	copy(key[:], dk)
 
 	return nacl.Seal(nil, shp, &nonce, &key)
 
 }
+
+// SealAEAD encrypts a msg with ChaCha20-Poly1305, using the given key, a zero-nonce and the given associated data - every key must only be used once
+func SealAEAD(dk, shp, ad []byte) ([]byte, error) {
+
+	aead, err := chacha20poly1305.New(dk)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var nonce [chacha20poly1305.NonceSize]byte // zero
+
+	return aead.Seal(nil, nonce[:], shp, ad), nil
+
+}
@@ -36,3 +36,47 @@ func TestEncryptDecrypt(t *testing.T) {
 	assert.Nil(out)
 
 }
+
+func TestDerive(t *testing.T) {
+
+	assert := assert.New(t)
+
+	a := Derive([]byte("sk"), []byte("ab"), []byte("c"))
+
+	assert.Len(a, KeySize)
+	assert.Equal(a, Derive([]byte("sk"), []byte("ab"), []byte("c")))
+
+	// values are length-prefixed, so moving bytes between them changes the key
+	assert.NotEqual(a, Derive([]byte("sk"), []byte("a"), []byte("bc")))
+	assert.NotEqual(a, Derive([]byte("sk2"), []byte("ab"), []byte("c")))
+
+	out, ok := Open(a, Seal(a, []byte("my secret")))
+
+	assert.True(ok)
+	assert.Equal("my secret", string(out))
+
+}
+
+func TestSealOpenAEAD(t *testing.T) {
+
+	assert := assert.New(t)
+
+	key := Derive([]byte("sk"))
+
+	shpe, err := SealAEAD(key, []byte("my secret"), []byte("ad"))
+
+	assert.NoError(err)
+
+	out, ok := OpenAEAD(key, shpe, []byte("ad"))
+
+	assert.True(ok)
+	assert.Equal("my secret", string(out))
+
+	out, ok = OpenAEAD(key, shpe, []byte("ae"))
+
+	assert.False(ok)
+	assert.Nil(out)
+
+	assert.NotEqual(Concat([]byte("ab"), []byte("c")), Concat([]byte("a"), []byte("bc")))
+
+}
@@ -1,14 +1,33 @@
 module github.com/kreuzwerker/yess
 
-go 1.14
+// crypto/ecdh, which parses and uses X25519 keys, requires Go 1.20 - since
+// Go 1.17, indirect dependencies are listed in a separate block
+go 1.20
 
 require (
 	github.com/davecgh/go-spew v1.1.1
-	github.com/hashicorp/vault v1.3.3
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/cobra v0.0.6
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.6.2
+	github.com/stretchr/testify v1.3.0
 	golang.org/x/crypto v0.0.0-20200311171314-f7b00557c8c4
+	gopkg.in/yaml.v2 v2.2.4
 	pault.ag/go/ykpiv v1.3.0
 )
+
+require (
+	github.com/fsnotify/fsnotify v1.4.7 // indirect
+	github.com/hashicorp/hcl v1.0.0 // indirect
+	github.com/magiconair/properties v1.8.1 // indirect
+	github.com/mitchellh/mapstructure v1.1.2 // indirect
+	github.com/pelletier/go-toml v1.2.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/spf13/afero v1.1.2 // indirect
+	github.com/spf13/cast v1.3.0 // indirect
+	github.com/spf13/jwalterweatherman v1.0.0 // indirect
+	github.com/subosito/gotenv v1.2.0 // indirect
+	golang.org/x/sys v0.0.0-20190813064441-fde4db37ae7a // indirect
+	golang.org/x/text v0.3.2 // indirect
+	gopkg.in/ini.v1 v1.51.0 // indirect
+)
@@ -0,0 +1,216 @@
+// Package recipient implements recipients based on exported key management certificates, allowing to split secrets without connecting the devices
+package recipient
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kreuzwerker/yess/device"
+	"github.com/kreuzwerker/yess/result"
+	"github.com/pkg/errors"
+)
+
+const (
+	errFailedToParseCertificate = "failed to parse certificate"
+	errFailedToParseSerial      = "failed to parse serial extension"
+	errFailedToReadCertificate  = "failed to read certificate %q"
+	errInvalidPEM               = "expected PEM block of type CERTIFICATE, got %q"
+	errNoSerial                 = "unable to determine device serial - certificate has no serial extension, please pass the serial along with the certificate (e.g. yk1.pem@1234567)"
+)
+
+// Recipient represents the key management certificate of a device that is not connected
+type Recipient struct {
+	cert   *x509.Certificate
+	serial uint32
+	slot   string
+}
+
+// Load loads a recipient from a PEM or DER encoded certificate file of the given slot - the device serial can be appended to the file name (e.g. yk1.pem@1234567) for certificates without a serial extension
+func Load(name, slot string) (*Recipient, error) {
+
+	var (
+		file   = name
+		serial uint32
+	)
+
+	// file names may contain an @ as well
+	if idx := strings.LastIndex(name, "@"); idx >= 0 {
+
+		if parsed, err := strconv.ParseUint(name[idx+1:], 10, 32); err == nil && parsed > 0 {
+			file = name[:idx]
+			serial = uint32(parsed)
+		}
+
+	}
+
+	in, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToReadCertificate, file)
+	}
+
+	var recipient *Recipient
+
+	if serial != 0 {
+		recipient, err = ParseWithSerial(in, serial, slot)
+	} else {
+		recipient, err = Parse(in, slot)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToReadCertificate, file)
+	}
+
+	return recipient, nil
+
+}
+
+// Parse parses a recipient from a PEM or DER encoded certificate of the given slot
+func Parse(in []byte, slot string) (*Recipient, error) {
+
+	cert, err := parse(in)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return New(cert, slot)
+
+}
+
+// ParseWithSerial parses a recipient from a PEM or DER encoded certificate of the given slot and a known device serial
+func ParseWithSerial(in []byte, serial uint32, slot string) (*Recipient, error) {
+
+	cert, err := parse(in)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithSerial(cert, serial, slot)
+
+}
+
+// New creates a recipient from a certificate of the given slot. The device serial is taken from the Yubico serial extension (present in attestation certificates), since the certificate serial number is unrelated to the device.
+func New(cert *x509.Certificate, slot string) (*Recipient, error) {
+
+	serial, err := serial(cert)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithSerial(cert, serial, slot)
+
+}
+
+// NewWithSerial creates a recipient from a certificate of the given slot and a known device serial, e.g. one recorded during enrollment
+func NewWithSerial(cert *x509.Certificate, serial uint32, slot string) (*Recipient, error) {
+
+	if slot == "" {
+		slot = device.DefaultSlot
+	}
+
+	if _, err := device.ParseSlot(slot); err != nil {
+		return nil, err
+	}
+
+	return &Recipient{
+		cert:   cert,
+		serial: serial,
+		slot:   slot,
+	}, nil
+
+}
+
+// Certificate returns the key management certificate
+func (r *Recipient) Certificate() *x509.Certificate {
+	return r.cert
+}
+
+// Encrypt encrypts the given share of a result into the part with the given index
+func (r *Recipient) Encrypt(res *result.Result, index int, msg []byte) (*result.Part, error) {
+	return device.Encrypt(r, res, index, msg)
+}
+
+// Serial returns the serial number of the device
+func (r *Recipient) Serial() uint32 {
+	return r.serial
+}
+
+// Slot returns the hex name of the slot
+func (r *Recipient) Slot() string {
+	return r.slot
+}
+
+// Vendor returns the vendor string of the device
+func (r *Recipient) Vendor() string {
+	return "Certificate"
+}
+
+// parse parses a PEM or DER encoded certificate
+func parse(in []byte) (*x509.Certificate, error) {
+
+	if bytes.HasPrefix(bytes.TrimSpace(in), []byte("-----BEGIN")) {
+
+		block, _ := pem.Decode(in)
+
+		if block == nil || block.Type != "CERTIFICATE" {
+
+			var t string
+
+			if block != nil {
+				t = block.Type
+			}
+
+			return nil, fmt.Errorf(errInvalidPEM, t)
+
+		}
+
+		in = block.Bytes
+
+	}
+
+	cert, err := x509.ParseCertificate(in)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToParseCertificate)
+	}
+
+	return cert, nil
+
+}
+
+// serial extracts the device serial from the serial extension of a certificate
+func serial(cert *x509.Certificate) (uint32, error) {
+
+	for _, ext := range cert.Extensions {
+
+		if !ext.Id.Equal(device.OIDSerial) {
+			continue
+		}
+
+		var serial int64
+
+		if _, err := asn1.Unmarshal(ext.Value, &serial); err != nil {
+			return 0, errors.Wrapf(err, errFailedToParseSerial)
+		}
+
+		if serial <= 0 || serial > math.MaxUint32 {
+			return 0, fmt.Errorf(errNoSerial)
+		}
+
+		return uint32(serial), nil
+
+	}
+
+	return 0, fmt.Errorf(errNoSerial)
+
+}
@@ -0,0 +1,146 @@
+package recipient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kreuzwerker/yess/device"
+	"github.com/kreuzwerker/yess/result"
+	"github.com/stretchr/testify/assert"
+)
+
+func certificate(t *testing.T, serial *big.Int, extensions ...pkix.Extension) []byte {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		ExtraExtensions: extensions,
+		NotAfter:        time.Date(2021, 2, 25, 0, 0, 0, 0, time.UTC),
+		NotBefore:       time.Date(2020, 2, 25, 0, 0, 0, 0, time.UTC),
+		SerialNumber:    serial,
+		Subject: pkix.Name{
+			CommonName: "mr. a",
+		},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return der
+
+}
+
+func TestParse(t *testing.T) {
+
+	assert := assert.New(t)
+
+	ext, err := asn1.Marshal(1234567)
+
+	assert.NoError(err)
+
+	large := new(big.Int).Lsh(big.NewInt(1), 100)
+
+	// serial from extension, DER encoded
+
+	r, err := Parse(certificate(t, large, pkix.Extension{
+		Id:    device.OIDSerial,
+		Value: ext,
+	}), "")
+
+	assert.NoError(err)
+	assert.Equal(uint32(1234567), r.Serial())
+	assert.Equal("9d", r.Slot())
+
+	// the certificate serial number is unrelated to the device serial
+
+	_, err = Parse(certificate(t, big.NewInt(42)), "")
+
+	assert.EqualError(err, "unable to determine device serial - certificate has no serial extension, please pass the serial along with the certificate (e.g. yk1.pem@1234567)")
+
+	// explicit serial, PEM encoded
+
+	r, err = ParseWithSerial(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: certificate(t, big.NewInt(42)),
+	}), 1234567, "82")
+
+	assert.NoError(err)
+	assert.Equal(uint32(1234567), r.Serial())
+
+	part, err := r.Encrypt(&result.Result{ID: "a"}, 1, []byte("my share"))
+
+	assert.NoError(err)
+	assert.Equal(uint32(1234567), part.Serial)
+	assert.Equal("CN=mr. a", part.Subject)
+	assert.Equal("2021-02-25T00:00:00Z", part.Expiry)
+	assert.Equal("82", part.Slot)
+
+	_, err = Parse(pem.EncodeToMemory(&pem.Block{
+		Type:  "EC PRIVATE KEY",
+		Bytes: []byte{0},
+	}), "")
+
+	assert.EqualError(err, `expected PEM block of type CERTIFICATE, got "EC PRIVATE KEY"`)
+
+	_, err = ParseWithSerial(certificate(t, big.NewInt(42)), 42, "9a")
+
+	assert.EqualError(err, `invalid slot "9a" - expected 9d or one of the retired key management slots 82 to 95`)
+
+}
+
+func TestLoad(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "recipient")
+
+	assert.NoError(err)
+
+	defer os.RemoveAll(dir)
+
+	// certificates of the same issuer may share a serial number
+	for _, name := range []string{"a@b.pem", "c.pem"} {
+
+		err := ioutil.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: certificate(t, big.NewInt(1)),
+		}), 0600)
+
+		assert.NoError(err)
+
+	}
+
+	a, err := Load(filepath.Join(dir, "a@b.pem@1234567"), "")
+
+	assert.NoError(err)
+	assert.Equal(uint32(1234567), a.Serial())
+
+	c, err := Load(filepath.Join(dir, "c.pem@7654321"), "")
+
+	assert.NoError(err)
+	assert.Equal(uint32(7654321), c.Serial())
+
+	_, err = Load(filepath.Join(dir, "a@b.pem"), "")
+
+	assert.EqualError(err, fmt.Sprintf("failed to read certificate %q: unable to determine device serial - certificate has no serial extension, please pass the serial along with the certificate (e.g. yk1.pem@1234567)", filepath.Join(dir, "a@b.pem")))
+
+}
@@ -0,0 +1,206 @@
+// Package registry implements a registry of holders and the key management certificates of their devices, which allows to reference recipients by name when splitting offline
+package registry
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/kreuzwerker/yess/recipient"
+	"github.com/pkg/errors"
+)
+
+const (
+	errDuplicateFingerprint     = "holder %q already uses a device with public key fingerprint %s"
+	errDuplicateName            = "holder %q already exists"
+	errDuplicateSerial          = "holder %q already uses a device with serial %d"
+	errFailedToDecode           = "failed to decode registry"
+	errFailedToOpen             = "failed to open registry %q"
+	errFailedToWrite            = "failed to write registry %q"
+	errFailedToParseCertificate = "failed to parse certificate of holder %q"
+	errUnknownHolder            = "unknown holder %q"
+)
+
+// Holder represents a holder and the device enrolled for them
+type Holder struct {
+	Certificate []byte `json:"certificate"` // Certificate is the DER representation of the key management certificate
+	Firmware    string `json:"firmware"`    // Firmware is the firmware version of the device at the time of enrollment
+	Name        string `json:"name"`        // Name identifies the holder
+	Serial      uint32 `json:"serial"`      // Serial is the devices serial number
+	Slot        string `json:"slot"`        // Slot is the hex name of the PIV slot holding the certificate
+}
+
+// Registry represents a list of holders
+type Registry struct {
+	Holders []*Holder `json:"holders"`
+}
+
+// Load loads a registry from a reader, e.g. a file
+func Load(r io.Reader) (*Registry, error) {
+
+	var registry Registry
+
+	r2 := json.NewDecoder(r)
+
+	if err := r2.Decode(&registry); err != nil {
+		return nil, errors.Wrapf(err, errFailedToDecode)
+	}
+
+	return &registry, nil
+
+}
+
+// LoadFile loads a registry from a file, returning an empty registry if the file does not exist
+func LoadFile(path string) (*Registry, error) {
+
+	f, err := os.Open(path)
+
+	if os.IsNotExist(err) {
+		return new(Registry), nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, errFailedToOpen, path)
+	}
+
+	defer f.Close()
+
+	return Load(f)
+
+}
+
+// Add adds a holder, refusing duplicate names, serials and public keys
+func (r *Registry) Add(h *Holder) error {
+
+	fingerprint, err := h.Fingerprint()
+
+	if err != nil {
+		return err
+	}
+
+	for _, e := range r.Holders {
+
+		if e.Name == h.Name {
+			return fmt.Errorf(errDuplicateName, e.Name)
+		}
+
+		if e.Serial == h.Serial {
+			return fmt.Errorf(errDuplicateSerial, e.Name, e.Serial)
+		}
+
+		other, err := e.Fingerprint()
+
+		if err != nil {
+			return err
+		}
+
+		if other == fingerprint {
+			return fmt.Errorf(errDuplicateFingerprint, e.Name, fingerprint)
+		}
+
+	}
+
+	r.Holders = append(r.Holders, h)
+
+	return nil
+
+}
+
+// Find returns the holder with the given name
+func (r *Registry) Find(name string) (*Holder, error) {
+
+	for _, h := range r.Holders {
+
+		if h.Name == name {
+			return h, nil
+		}
+
+	}
+
+	return nil, fmt.Errorf(errUnknownHolder, name)
+
+}
+
+// Save stores a registry in a writer, e.g. a file
+func (r *Registry) Save(w io.Writer) error {
+
+	w2 := json.NewEncoder(w)
+
+	w2.SetIndent("", "\t")
+
+	return w2.Encode(r)
+
+}
+
+// SaveFile atomically replaces the given file with the registry
+func (r *Registry) SaveFile(path string) error {
+
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+
+	if err != nil {
+		return errors.Wrapf(err, errFailedToWrite, path)
+	}
+
+	defer os.Remove(f.Name())
+
+	if err := r.Save(f); err != nil {
+		f.Close()
+		return errors.Wrapf(err, errFailedToWrite, path)
+	}
+
+	if err := f.Close(); err != nil {
+		return errors.Wrapf(err, errFailedToWrite, path)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return errors.Wrapf(err, errFailedToWrite, path)
+	}
+
+	return nil
+
+}
+
+// Fingerprint returns the hex encoded SHA-256 hash of the PKIX (DER) representation of the public key
+func (h *Holder) Fingerprint() (string, error) {
+
+	cert, err := h.certificate()
+
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	return hex.EncodeToString(sum[:]), nil
+
+}
+
+// Recipient returns a recipient for the enrolled device
+func (h *Holder) Recipient() (*recipient.Recipient, error) {
+
+	cert, err := h.certificate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return recipient.NewWithSerial(cert, h.Serial, h.Slot)
+
+}
+
+// certificate parses the key management certificate
+func (h *Holder) certificate() (*x509.Certificate, error) {
+
+	cert, err := x509.ParseCertificate(h.Certificate)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToParseCertificate, h.Name)
+	}
+
+	return cert, nil
+
+}
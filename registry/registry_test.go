@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"testing"
+
+	"github.com/kreuzwerker/yess/soft"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddAndFind(t *testing.T) {
+
+	assert := assert.New(t)
+
+	a, err := soft.Generate(elliptic.P256(), 1, soft.DefaultPIN)
+
+	assert.NoError(err)
+
+	b, err := soft.Generate(elliptic.P384(), 2, soft.DefaultPIN)
+
+	assert.NoError(err)
+
+	registry := new(Registry)
+
+	assert.NoError(registry.Add(&Holder{
+		Certificate: a.Certificate().Raw,
+		Firmware:    "5.2.4",
+		Name:        "a",
+		Serial:      1,
+	}))
+
+	assert.EqualError(registry.Add(&Holder{
+		Certificate: b.Certificate().Raw,
+		Name:        "a",
+		Serial:      2,
+	}), `holder "a" already exists`)
+
+	assert.EqualError(registry.Add(&Holder{
+		Certificate: b.Certificate().Raw,
+		Name:        "b",
+		Serial:      1,
+	}), `holder "a" already uses a device with serial 1`)
+
+	err = registry.Add(&Holder{
+		Certificate: a.Certificate().Raw,
+		Name:        "b",
+		Serial:      2,
+	})
+
+	assert.Error(err)
+	assert.Contains(err.Error(), `holder "a" already uses a device with public key fingerprint`)
+
+	assert.NoError(registry.Add(&Holder{
+		Certificate: b.Certificate().Raw,
+		Name:        "b",
+		Serial:      2,
+	}))
+
+	var buf bytes.Buffer
+
+	assert.NoError(registry.Save(&buf))
+
+	registry, err = Load(&buf)
+
+	assert.NoError(err)
+	assert.Len(registry.Holders, 2)
+
+	holder, err := registry.Find("b")
+
+	assert.NoError(err)
+	assert.Equal(uint32(2), holder.Serial)
+
+	recipient, err := holder.Recipient()
+
+	assert.NoError(err)
+	assert.Equal(uint32(2), recipient.Serial())
+	assert.Equal(b.Certificate().Raw, recipient.Certificate().Raw)
+
+	_, err = registry.Find("c")
+
+	assert.EqualError(err, `unknown holder "c"`)
+
+}
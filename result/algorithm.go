@@ -0,0 +1,42 @@
+package result
+
+// Algorithm describes how the key encrypting a share was established and how the share was encrypted with it
+type Algorithm struct {
+	Cipher       string `json:"cipher"`       // Cipher encrypts the share with the derived key
+	KDF          string `json:"kdf"`          // KDF derives the key from the secret established by the key agreement
+	KeyAgreement string `json:"keyAgreement"` // KeyAgreement establishes the secret shared with the device
+}
+
+const (
+	// KeyAgreementECDH establishes the secret through an ECDH key exchange between an ephemeral key and the devices NIST curve key
+	KeyAgreementECDH = "ecdh"
+	// KeyAgreementRSA establishes the secret through a random key encrypted to the devices key using RSA with PKCS #1 v1.5 padding
+	KeyAgreementRSA = "rsa-pkcs1v15"
+	// KeyAgreementX25519 establishes the secret through an X25519 key exchange between an ephemeral key and the devices key
+	KeyAgreementX25519 = "x25519"
+)
+
+const (
+	// KDFHKDF derives the key with HKDF-SHA256 from the fixed-length secret, binding the ephemeral (or wrapped) key, the recipient key and the result ID
+	KDFHKDF = "hkdf-sha256"
+	// KDFSHA3 derives the key as the SHA3-256 digest of the secret, which is the minimal encoding of the x-coordinate for ECDH (version 1)
+	KDFSHA3 = "sha3-256"
+)
+
+const (
+	// CipherChaCha20Poly1305 encrypts the share with ChaCha20-Poly1305 and a zero nonce, authenticating the ID, threshold, mode, sharing and commitments of the result and the index, serial, recipient key, issuer, subject and expiry of the part as associated data - the x-coordinates and weight of the part and the policy of the result are not covered
+	CipherChaCha20Poly1305 = "chacha20-poly1305"
+	// CipherSecretbox encrypts the share with NaCl secretbox (XSalsa20 and Poly1305) and a zero nonce
+	CipherSecretbox = "xsalsa20-poly1305"
+)
+
+// NewAlgorithm returns the descriptor for the given key agreement, using the current KDF and cipher
+func NewAlgorithm(keyAgreement string) Algorithm {
+
+	return Algorithm{
+		Cipher:       CipherChaCha20Poly1305,
+		KDF:          KDFHKDF,
+		KeyAgreement: keyAgreement,
+	}
+
+}
@@ -8,13 +8,21 @@ import (
 
 // Part represents one share of the secret. Except for the share and the public key field all fields are just present for informational purposes (even the expiry).
 type Part struct {
-	Device    string `json:"device"`    // Device identifies the device through it's vendor string
-	Expiry    string `json:"expiry"`    // Expiry is the RFC3339 representation of the certificates expiry date
-	Issuer    string `json:"issuer"`    // Issuer is the certificates isser
-	PublicKey []byte `json:"publicKey"` // PublicKey is a PKIX (DER) representation of the public key used for the shared key exchange
-	Serial    uint32 `json:"serial"`    // Serial is the devices serial number (often printed on the device itself)
-	Share     []byte `json:"share"`     // Share is the encrypted Shamir share
-	Subject   string `json:"subject"`   // Subject is the certificate subject
+	Algorithm Algorithm `json:"algorithm"`           // Algorithm describes how the share was encrypted
+	Backups   []*Part   `json:"backups,omitempty"`   // Backups are further stanzas of the part, encrypting the same shares to backup devices of the holder
+	Device    string    `json:"device"`              // Device identifies the device through it's vendor string
+	Expiry    string    `json:"expiry"`              // Expiry is the RFC3339 representation of the certificates expiry date
+	Index     int       `json:"index,omitempty"`     // Index is the 1-based position of the part in the result (CipherChaCha20Poly1305 only)
+	Issuer    string    `json:"issuer"`              // Issuer is the certificates isser
+	PublicKey []byte    `json:"publicKey,omitempty"` // PublicKey is a PKIX (DER) representation of the public key used for the shared key exchange
+	Recipient []byte    `json:"recipient,omitempty"` // Recipient is a PKIX (DER) representation of the devices public key (KDFHKDF only)
+	Serial    uint32    `json:"serial"`              // Serial is the devices serial number (often printed on the device itself)
+	Share     []byte    `json:"share"`               // Share is the encrypted Shamir share
+	Slot      string    `json:"slot,omitempty"`      // Slot is the hex name of the PIV slot holding the devices key, defaulting to 9d (key management)
+	Subject   string    `json:"subject"`             // Subject is the certificate subject
+	Weight    int       `json:"weight,omitempty"`    // Weight is the number of shares encrypted in the part, defaulting to 1
+	Wrapped   []byte    `json:"wrapped,omitempty"`   // Wrapped is the key encrypting the share, encrypted to the devices public key (KeyAgreementRSA only)
+	X         int       `json:"x,omitempty"`         // X is the x-coordinate of the (first) share, which is unknown for parts of older results
 }
 
 const (
@@ -49,3 +57,19 @@ func (p *Part) Key() (interface{}, error) {
 	return out, nil
 
 }
+
+// Shares returns the number of shares encrypted in the part
+func (p *Part) Shares() int {
+
+	if p.Weight > 1 {
+		return p.Weight
+	}
+
+	return 1
+
+}
+
+// Stanzas returns the part followed by its backups
+func (p *Part) Stanzas() []*Part {
+	return append([]*Part{p}, p.Backups...)
+}
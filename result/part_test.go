@@ -1,7 +1,9 @@
 package result
 
 import (
+	"crypto/ecdh"
 	"crypto/ecdsa"
+	"crypto/rand"
 	"encoding/base64"
 	"testing"
 
@@ -44,3 +46,26 @@ func TestAddAndGetKey(t *testing.T) {
 	}
 
 }
+
+func TestAddAndGetX25519Key(t *testing.T) {
+
+	assert := assert.New(t)
+
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+
+	assert.NoError(err)
+
+	part := new(Part)
+
+	assert.NoError(part.AddKey(key.PublicKey()))
+
+	out, err := part.Key()
+
+	assert.NoError(err)
+
+	pk, ok := out.(*ecdh.PublicKey)
+
+	assert.True(ok)
+	assert.True(key.PublicKey().Equal(pk))
+
+}
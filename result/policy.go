@@ -0,0 +1,82 @@
+package result
+
+import "fmt"
+
+const (
+	errInvalidGroupThreshold = "invalid threshold %d for %d members of group %q"
+	errPolicyNotCovered      = "part of device %d is not covered by the policy"
+	errPolicySerial          = "serial %d appears more than once in the policy or has no part"
+	errPolicyThreshold       = "threshold %d does not match the threshold %d of the policy"
+)
+
+// Group is a node of a policy tree - it is satisfied once Threshold of its members, i.e. its subgroups followed by the holders with the given serials, are satisfied
+type Group struct {
+	Groups    []*Group `json:"groups,omitempty"`  // Groups are the subgroups, which receive the first shares of the group
+	Name      string   `json:"name"`              // Name identifies the group towards the holders
+	Serials   []uint32 `json:"serials,omitempty"` // Serials are the devices of the holders, which receive the remaining shares of the group
+	Threshold int      `json:"threshold"`         // Threshold is the number of members required to recover the share of the group
+}
+
+// Members returns the number of subgroups and holders of the group
+func (g *Group) Members() int {
+	return len(g.Groups) + len(g.Serials)
+}
+
+// validatePolicy checks the thresholds of the policy and that every part belongs to exactly one holder
+func (r *Result) validatePolicy() error {
+
+	if r.Policy.Threshold != r.Threshold {
+		return fmt.Errorf(errPolicyThreshold, r.Threshold, r.Policy.Threshold)
+	}
+
+	serials := make(map[uint32]int)
+
+	for _, p := range r.Parts {
+		serials[p.Serial] = 0
+	}
+
+	var walk func(g *Group) error
+
+	walk = func(g *Group) error {
+
+		if g.Threshold < 1 || g.Threshold > g.Members() {
+			return fmt.Errorf(errInvalidGroupThreshold, g.Threshold, g.Members(), g.Name)
+		}
+
+		for _, serial := range g.Serials {
+
+			if count, ok := serials[serial]; !ok || count > 0 {
+				return fmt.Errorf(errPolicySerial, serial)
+			}
+
+			serials[serial]++
+
+		}
+
+		for _, sub := range g.Groups {
+
+			if err := walk(sub); err != nil {
+				return err
+			}
+
+		}
+
+		return nil
+
+	}
+
+	if err := walk(r.Policy); err != nil {
+		return err
+	}
+
+	for _, p := range r.Parts {
+
+		if serials[p.Serial] == 0 {
+			return fmt.Errorf(errPolicyNotCovered, p.Serial)
+		}
+
+	}
+
+	return nil
+
+}
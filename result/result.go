@@ -1,32 +1,140 @@
 package result
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
+	"io/ioutil"
 
 	"github.com/pkg/errors"
 )
 
-const errFailedToDecode = "failed to decode result"
+const (
+	errFailedToDecode     = "failed to decode result"
+	errFailedToEncode     = "failed to encode result"
+	errFailedToGenerateID = "failed to generate result ID"
+	errFailedToReadResult = "failed to read result"
+	errUnsupportedVersion = "unsupported result version %d - the latest supported version is %d"
+)
+
+const (
+	// Version1 is the initial format without version and algorithm identifiers
+	Version1 = 1
+	// Version2 adds the version and an algorithm descriptor to every part
+	Version2 = 2
+	// Version is the version of newly created results
+	Version = Version2
+)
 
 // Result represents the result of a split into n parts with the given threshold.
 type Result struct {
-	Parts     []*Part `json:"parts"`
-	Threshold int     `json:"threshold"`
+	Commitments [][]byte `json:"commitments,omitempty"` // Commitments are the compressed points committing to the coefficients of the sharing polynomial (SharingFeldman only)
+	ID          string   `json:"id,omitempty"`          // ID identifies the result and is bound into the key derivation of its parts (KDFHKDF only)
+	Mode        string   `json:"mode,omitempty"`        // Mode identifies what has been split, defaulting to ModeSecret
+	Parts       []*Part  `json:"parts"`                 // Parts are the encrypted shares, one per device
+	Payload     []byte   `json:"payload,omitempty"`     // Payload is the secret encrypted with the DEK, unless stored in a sidecar file (ModeDEK only)
+	Policy      *Group   `json:"policy,omitempty"`      // Policy is the tree of groups the secret has been split for, if any
+	Previous    string   `json:"previous,omitempty"`    // Previous is the ID of the result this result has been reshared from, if any
+	Sharing     string   `json:"sharing,omitempty"`     // Sharing identifies the secret sharing scheme, defaulting to SharingShamir
+	Threshold   int      `json:"threshold"`             // Threshold is the number of parts required for reconstruction
+	Version     int      `json:"version"`               // Version is the version of the format
 }
 
-// Load loads a result from a reader, e.g. a file
+const (
+	// ModeDEK splits a random data encryption key (DEK), which encrypts the secret into the payload
+	ModeDEK = "dek"
+	// ModeSecret splits the secret itself
+	ModeSecret = ""
+	// ModeStream splits a random DEK, which encrypts the secret in chunks into a separate file
+	ModeStream = "stream"
+)
+
+const (
+	// SharingFeldman is Feldman verifiable secret sharing over the scalar field of P-256, which requires a DEK
+	SharingFeldman = "feldman-p256"
+	// SharingShamir is Shamir secret sharing over GF(256) with an appended hash, limited to 255 parts
+	SharingShamir = ""
+	// SharingShamirP256 is Shamir secret sharing over the scalar field of P-256 with an appended hash, which allows more than 255 parts
+	SharingShamirP256 = "shamir-p256"
+)
+
+// New returns an empty result in the current version with a random ID
+func New(threshold int) (*Result, error) {
+
+	id, err := NewID()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		ID:        id,
+		Threshold: threshold,
+		Version:   Version,
+	}, nil
+
+}
+
+// NewID returns a random result ID
+func NewID() (string, error) {
+
+	id := make([]byte, 16)
+
+	if _, err := rand.Read(id); err != nil {
+		return "", errors.Wrapf(err, errFailedToGenerateID)
+	}
+
+	return hex.EncodeToString(id), nil
+
+}
+
+// Find returns the part of the holder of the device with the given serial and the stanza of the part encrypted to that device, which is the part itself unless the device is a backup device
+func (r *Result) Find(serial uint32) (*Part, *Part) {
+
+	for _, part := range r.Parts {
+
+		for _, stanza := range part.Stanzas() {
+
+			if stanza.Serial == serial {
+				return part, stanza
+			}
+
+		}
+
+	}
+
+	return nil, nil
+
+}
+
+// Load loads a result of any supported version from a reader, e.g. a file, and upgrades it to the current version
 func Load(r io.Reader) (*Result, error) {
 
-	var result Result
+	in, err := ioutil.ReadAll(r)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToReadResult)
+	}
 
-	r2 := json.NewDecoder(r)
+	var header struct {
+		Version int `json:"version"`
+	}
 
-	if err := r2.Decode(&result); err != nil {
+	if err := json.Unmarshal(in, &header); err != nil {
 		return nil, errors.Wrapf(err, errFailedToDecode)
 	}
 
-	return &result, nil
+	switch header.Version {
+	case 0, Version1:
+		// results created before the introduction of versions lack the field
+		return loadV1(in)
+	case Version2:
+		return loadV2(in)
+	default:
+		return nil, fmt.Errorf(errUnsupportedVersion, header.Version, Version)
+	}
 
 }
 
@@ -36,8 +144,24 @@ func (r *Result) Save(w io.Writer) error {
 	w2 := json.NewEncoder(w)
 
 	w2.SetIndent("", "\t")
-	w2.Encode(r)
+
+	if err := w2.Encode(r); err != nil {
+		return errors.Wrapf(err, errFailedToEncode)
+	}
 
 	return nil
 
 }
+
+// loadV2 decodes a version 2 result
+func loadV2(in []byte) (*Result, error) {
+
+	var result Result
+
+	if err := json.Unmarshal(in, &result); err != nil {
+		return nil, errors.Wrapf(err, errFailedToDecode)
+	}
+
+	return &result, nil
+
+}
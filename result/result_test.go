@@ -1,6 +1,7 @@
 package result
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"strings"
 	"testing"
@@ -56,6 +57,7 @@ func TestResultLoadAndSave(t *testing.T) {
 	assert.NotNil(result)
 
 	assert.Equal(2, result.Threshold)
+	assert.Equal(Version, result.Version)
 
 	mapping := make(map[uint32]*Part)
 
@@ -74,6 +76,7 @@ func TestResultLoadAndSave(t *testing.T) {
 	key, _ := mapping[1].Key()
 	assert.Equal("P-384", key.(*ecdsa.PublicKey).Params().Name)
 
+	assert.Equal(Algorithm{CipherSecretbox, KDFSHA3, KeyAgreementECDH}, mapping[1].Algorithm)
 	assert.Equal(uint32(1), mapping[1].Serial)
 	assert.Equal(66, len(mapping[1].Share))
 	assert.Equal("CN=mr. a", mapping[1].Subject)
@@ -105,3 +108,34 @@ func TestResultLoadAndSave(t *testing.T) {
 	assert.Equal("CN=mrs. c", mapping[3].Subject)
 
 }
+
+func TestResultVersions(t *testing.T) {
+
+	assert := assert.New(t)
+
+	result, err := Load(strings.NewReader(`{"parts": [{"scheme": "rsa-pkcs1v15", "serial": 1}], "threshold": 1, "version": 1}`))
+
+	assert.NoError(err)
+	assert.Equal(Version, result.Version)
+	assert.Equal(Algorithm{CipherSecretbox, KDFSHA3, KeyAgreementRSA}, result.Parts[0].Algorithm)
+
+	var buf bytes.Buffer
+
+	assert.NoError(result.Save(&buf))
+	assert.NotContains(buf.String(), "scheme")
+
+	result, err = Load(&buf)
+
+	assert.NoError(err)
+	assert.Equal(Version, result.Version)
+	assert.Equal(KeyAgreementRSA, result.Parts[0].Algorithm.KeyAgreement)
+
+	_, err = Load(strings.NewReader(`{"parts": [{"scheme": "rot13", "serial": 1}], "threshold": 1}`))
+
+	assert.EqualError(err, `unsupported scheme "rot13" in version 1 part of device 1`)
+
+	_, err = Load(strings.NewReader(`{"parts": [], "threshold": 1, "version": 3}`))
+
+	assert.EqualError(err, "unsupported result version 3 - the latest supported version is 2")
+
+}
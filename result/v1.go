@@ -0,0 +1,94 @@
+package result
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errUnsupportedKeyV1    = "unsupported public key type %T in version 1 part of device %d"
+	errUnsupportedSchemeV1 = "unsupported scheme %q in version 1 part of device %d"
+)
+
+// partV1 is a part of a version 1 result, which identifies the key agreement through an optional scheme
+type partV1 struct {
+	Part
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// loadV1 decodes a version 1 result and derives the algorithm descriptors of its parts
+func loadV1(in []byte) (*Result, error) {
+
+	var v1 struct {
+		Parts     []*partV1 `json:"parts"`
+		Threshold int       `json:"threshold"`
+	}
+
+	if err := json.Unmarshal(in, &v1); err != nil {
+		return nil, errors.Wrapf(err, errFailedToDecode)
+	}
+
+	result := &Result{
+		Threshold: v1.Threshold,
+		Version:   Version,
+	}
+
+	for _, p := range v1.Parts {
+
+		keyAgreement, err := p.keyAgreement()
+
+		if err != nil {
+			return nil, err
+		}
+
+		part := p.Part
+
+		part.Algorithm = Algorithm{
+			Cipher:       CipherSecretbox,
+			KDF:          KDFSHA3,
+			KeyAgreement: keyAgreement,
+		}
+
+		result.Parts = append(result.Parts, &part)
+
+	}
+
+	return result, nil
+
+}
+
+// keyAgreement derives the key agreement of a version 1 part - parts without a scheme always use ECDH, either on NIST curves or X25519
+func (p *partV1) keyAgreement() (string, error) {
+
+	switch p.Scheme {
+	case "", KeyAgreementECDH:
+	case KeyAgreementRSA:
+		return KeyAgreementRSA, nil
+	default:
+		return "", fmt.Errorf(errUnsupportedSchemeV1, p.Scheme, p.Serial)
+	}
+
+	pk, err := p.Key()
+
+	if err != nil {
+		return "", err
+	}
+
+	switch t := pk.(type) {
+	case *ecdsa.PublicKey:
+		return KeyAgreementECDH, nil
+	case *ecdh.PublicKey:
+
+		if t.Curve() == ecdh.X25519() {
+			return KeyAgreementX25519, nil
+		}
+
+	}
+
+	return "", fmt.Errorf(errUnsupportedKeyV1, pk, p.Serial)
+
+}
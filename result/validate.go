@@ -0,0 +1,154 @@
+package result
+
+import "fmt"
+
+const (
+	errDuplicateIndex     = "duplicate index %d in parts of devices %d and %d"
+	errDuplicateSerial    = "duplicate serial %d in parts"
+	errDuplicateX         = "duplicate x-coordinate %d in parts of devices %d and %d"
+	errInvalidBackup      = "backup device %d does not match the part of device %d"
+	errInvalidCommitments = "%d commitments do not match the threshold %d"
+	errInvalidIndex       = "invalid index %d in part of device %d"
+	errInvalidThreshold   = "invalid threshold %d for %d shares"
+	errInvalidWeight      = "invalid weight %d of part of device %d"
+	errInvalidX           = "invalid x-coordinate %d in part of device %d"
+	errMissingID          = "result has no ID, but part of device %d is authenticated with it"
+	errMissingPayloadID   = "result has no ID, but its payload is authenticated with it"
+	errMissingRecipient   = "part of device %d has no recipient key"
+	errUnknownMode        = "unknown mode %q"
+	errUnknownSharing     = "unknown sharing %q"
+	errUnsupportedPolicy  = "sharing %q does not support policies"
+	errUnsupportedSharing = "sharing %q requires a DEK"
+)
+
+// Validate checks the structure of a result without any device - tampering with the authenticated fields of a part is only detected when decrypting its share
+func (r *Result) Validate() error {
+
+	var shares int
+
+	for _, p := range r.Parts {
+
+		if p.Weight < 0 {
+			return fmt.Errorf(errInvalidWeight, p.Weight, p.Serial)
+		}
+
+		shares += p.Shares()
+
+	}
+
+	if r.Threshold < 1 || r.Threshold > shares {
+		return fmt.Errorf(errInvalidThreshold, r.Threshold, shares)
+	}
+
+	switch r.Mode {
+	case ModeSecret:
+	case ModeDEK, ModeStream:
+
+		if r.ID == "" {
+			return fmt.Errorf(errMissingPayloadID)
+		}
+
+	default:
+		return fmt.Errorf(errUnknownMode, r.Mode)
+	}
+
+	if r.Policy != nil {
+
+		if r.Sharing == SharingFeldman {
+			return fmt.Errorf(errUnsupportedPolicy, r.Sharing)
+		}
+
+		if err := r.validatePolicy(); err != nil {
+			return err
+		}
+
+	}
+
+	switch r.Sharing {
+	case SharingShamir, SharingShamirP256:
+	case SharingFeldman:
+
+		if r.Mode == ModeSecret {
+			return fmt.Errorf(errUnsupportedSharing, r.Sharing)
+		}
+
+		if len(r.Commitments) != r.Threshold {
+			return fmt.Errorf(errInvalidCommitments, len(r.Commitments), r.Threshold)
+		}
+
+	default:
+		return fmt.Errorf(errUnknownSharing, r.Sharing)
+	}
+
+	var (
+		indices = make(map[int]uint32)
+		serials = make(map[uint32]bool)
+		xs      = make(map[int]uint32)
+	)
+
+	for _, p := range r.Parts {
+
+		for _, stanza := range p.Stanzas() {
+
+			if serials[stanza.Serial] {
+				return fmt.Errorf(errDuplicateSerial, stanza.Serial)
+			}
+
+			serials[stanza.Serial] = true
+
+		}
+
+		for _, backup := range p.Backups {
+
+			if len(backup.Backups) > 0 || backup.Index != p.Index || backup.Weight != p.Weight || backup.X != p.X {
+				return fmt.Errorf(errInvalidBackup, backup.Serial, p.Serial)
+			}
+
+			if backup.Algorithm.Cipher == CipherChaCha20Poly1305 && len(backup.Recipient) == 0 {
+				return fmt.Errorf(errMissingRecipient, backup.Serial)
+			}
+
+		}
+
+		// weighted parts hold consecutive x-coordinates
+		for x := p.X; x < p.X+p.Shares() && p.X != 0; x++ {
+
+			if x < 0 || (r.Sharing == SharingShamir && x > 255) {
+				return fmt.Errorf(errInvalidX, x, p.Serial)
+			}
+
+			if serial, ok := xs[x]; ok {
+				return fmt.Errorf(errDuplicateX, x, serial, p.Serial)
+			}
+
+			xs[x] = p.Serial
+
+		}
+
+		if p.Algorithm.Cipher != CipherChaCha20Poly1305 {
+			continue
+		}
+
+		if r.ID == "" {
+			return fmt.Errorf(errMissingID, p.Serial)
+		}
+
+		if len(p.Recipient) == 0 {
+			return fmt.Errorf(errMissingRecipient, p.Serial)
+		}
+
+		if p.Index < 1 {
+			return fmt.Errorf(errInvalidIndex, p.Index, p.Serial)
+		}
+
+		if serial, ok := indices[p.Index]; ok {
+			return fmt.Errorf(errDuplicateIndex, p.Index, serial, p.Serial)
+		}
+
+		indices[p.Index] = p.Serial
+
+	}
+
+	return nil
+
+}
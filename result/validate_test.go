@@ -0,0 +1,168 @@
+package result
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+
+	assert := assert.New(t)
+
+	part := func(serial uint32, index int) *Part {
+
+		return &Part{
+			Algorithm: NewAlgorithm(KeyAgreementECDH),
+			Index:     index,
+			Recipient: []byte{1},
+			Serial:    serial,
+		}
+
+	}
+
+	res := &Result{
+		ID:        "a",
+		Parts:     []*Part{part(1, 1), part(2, 2)},
+		Threshold: 2,
+	}
+
+	assert.NoError(res.Validate())
+
+	res.Threshold = 3
+	assert.EqualError(res.Validate(), "invalid threshold 3 for 2 shares")
+
+	res.Threshold = 0
+	assert.EqualError(res.Validate(), "invalid threshold 0 for 2 shares")
+
+	res.Threshold = 2
+	res.Parts[1].Index = 1
+	assert.EqualError(res.Validate(), "duplicate index 1 in parts of devices 1 and 2")
+
+	res.Parts[1].Serial = 1
+	assert.EqualError(res.Validate(), "duplicate serial 1 in parts")
+
+	res.Parts[1] = part(2, 0)
+	assert.EqualError(res.Validate(), "invalid index 0 in part of device 2")
+
+	res.Parts[1] = part(2, 2)
+	res.Parts[0].X = 256
+	assert.EqualError(res.Validate(), "invalid x-coordinate 256 in part of device 1")
+
+	res.Parts[0].X = 2
+	res.Parts[1].X = 2
+	assert.EqualError(res.Validate(), "duplicate x-coordinate 2 in parts of devices 1 and 2")
+
+	res.Parts[0].X = 1
+	assert.NoError(res.Validate())
+
+	res.Parts[1].Recipient = nil
+	assert.EqualError(res.Validate(), "part of device 2 has no recipient key")
+
+	res.Parts[1].Recipient = []byte{1}
+	res.ID = ""
+	assert.EqualError(res.Validate(), "result has no ID, but part of device 1 is authenticated with it")
+
+	// version 1 parts are not authenticated
+	res.Parts[0].Algorithm = Algorithm{CipherSecretbox, KDFSHA3, KeyAgreementECDH}
+	res.Parts[1].Algorithm = Algorithm{CipherSecretbox, KDFSHA3, KeyAgreementECDH}
+	assert.NoError(res.Validate())
+
+	res.ID = "a"
+	res.Sharing = SharingFeldman
+	assert.EqualError(res.Validate(), `sharing "feldman-p256" requires a DEK`)
+
+	res.Mode = ModeDEK
+	assert.EqualError(res.Validate(), "0 commitments do not match the threshold 2")
+
+	res.Commitments = [][]byte{{1}, {2}}
+	assert.NoError(res.Validate())
+
+	res.Sharing = "unknown"
+	assert.EqualError(res.Validate(), `unknown sharing "unknown"`)
+
+}
+
+func TestValidatePolicy(t *testing.T) {
+
+	assert := assert.New(t)
+
+	res := &Result{
+		ID: "a",
+		Parts: []*Part{
+			{Serial: 1},
+			{Serial: 2},
+			{Serial: 3},
+		},
+		Policy: &Group{
+			Groups: []*Group{
+				{Name: "engineers", Serials: []uint32{1, 2}, Threshold: 1},
+			},
+			Name:      "policy",
+			Serials:   []uint32{3},
+			Threshold: 2,
+		},
+		Threshold: 2,
+	}
+
+	assert.NoError(res.Validate())
+
+	res.Threshold = 1
+	assert.EqualError(res.Validate(), "threshold 1 does not match the threshold 2 of the policy")
+
+	res.Threshold = 2
+	res.Policy.Groups[0].Threshold = 3
+	assert.EqualError(res.Validate(), `invalid threshold 3 for 2 members of group "engineers"`)
+
+	res.Policy.Groups[0].Threshold = 1
+	res.Policy.Serials = []uint32{2}
+	assert.EqualError(res.Validate(), "serial 2 appears more than once in the policy or has no part")
+
+	res.Policy.Serials = nil
+	res.Policy.Groups[0].Serials = []uint32{1, 2, 3}
+	assert.EqualError(res.Validate(), `invalid threshold 2 for 1 members of group "policy"`)
+
+	res.Policy.Serials = []uint32{4}
+	assert.EqualError(res.Validate(), "serial 4 appears more than once in the policy or has no part")
+
+	res.Policy.Serials = nil
+	res.Policy.Threshold = 1
+	res.Threshold = 1
+	res.Parts = append(res.Parts, &Part{Serial: 4})
+	assert.EqualError(res.Validate(), "part of device 4 is not covered by the policy")
+
+}
+
+func TestValidateBackups(t *testing.T) {
+
+	assert := assert.New(t)
+
+	res := &Result{
+		ID: "a",
+		Parts: []*Part{
+			{Backups: []*Part{{Serial: 3, X: 1}}, Serial: 1, X: 1},
+			{Serial: 2, X: 2},
+		},
+		Threshold: 2,
+	}
+
+	assert.NoError(res.Validate())
+
+	part, stanza := res.Find(3)
+
+	assert.Equal(uint32(1), part.Serial)
+	assert.Equal(uint32(3), stanza.Serial)
+
+	part, stanza = res.Find(4)
+
+	assert.Nil(part)
+	assert.Nil(stanza)
+
+	res.Parts[0].Backups[0].X = 2
+	assert.EqualError(res.Validate(), "backup device 3 does not match the part of device 1")
+
+	res.Parts[0].Backups[0].X = 1
+	res.Parts[0].Backups[0].Serial = 2
+	assert.EqualError(res.Validate(), "duplicate serial 2 in parts")
+
+}
@@ -0,0 +1,45 @@
+package share
+
+// GF(2^8) arithmetic with the AES polynomial x^8 + x^4 + x^3 + x + 1, which is also used by shares of older results - all operations avoid lookup tables and branches on secret values to run in constant time
+
+// add adds (and subtracts) two elements
+func add(a, b uint8) uint8 {
+	return a ^ b
+}
+
+// div divides a by b, which must not be zero
+func div(a, b uint8) uint8 {
+	return mul(a, inv(b))
+}
+
+// inv returns the multiplicative inverse as b^254, which maps zero to zero
+func inv(b uint8) uint8 {
+
+	var (
+		b2   = mul(b, b)
+		b4   = mul(b2, b2)
+		b8   = mul(b4, b4)
+		b16  = mul(b8, b8)
+		b32  = mul(b16, b16)
+		b64  = mul(b32, b32)
+		b128 = mul(b64, b64)
+	)
+
+	return mul(mul(mul(mul(mul(mul(b128, b64), b32), b16), b8), b4), b2)
+
+}
+
+// mul multiplies two elements with masks instead of branches
+func mul(a, b uint8) uint8 {
+
+	var p uint8
+
+	for i := 0; i < 8; i++ {
+		p ^= -(b & 1) & a
+		a = a<<1 ^ -(a>>7)&0x1b
+		b >>= 1
+	}
+
+	return p
+
+}
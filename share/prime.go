@@ -0,0 +1,246 @@
+package share
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errInvalidPadding     = "invalid padding, parts are missing"
+	errInvalidPrimeLength = "invalid part length %d"
+)
+
+const (
+	// blockSize is the size of the secret blocks, which are all below the prime
+	blockSize = 31
+	// elementSize is the size of the encoded field elements
+	elementSize = 32
+	// xSize is the size of the encoded x-coordinate
+	xSize = 4
+)
+
+// prime is the order of P-256, a 256 bit prime
+var prime = elliptic.P256().Params().N
+
+// CombinePrime attempts to combine the given parts of SplitPrime
+func CombinePrime(shps [][]byte) ([]byte, error) {
+
+	data, err := interpolatePrime(shps, new(big.Int))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var padded []byte
+
+	for i := 0; i < len(data); i += elementSize {
+
+		block := new(big.Int).SetBytes(data[i : i+elementSize])
+
+		// blocks of missing or foreign parts are random field elements
+		if block.BitLen() > blockSize*8 {
+			return nil, fmt.Errorf(errInvalidHash)
+		}
+
+		padded = append(padded, block.FillBytes(make([]byte, blockSize))...)
+
+	}
+
+	end := bytes.LastIndexByte(padded, 0x80)
+
+	if end < hashSize || len(bytes.TrimLeft(padded[end+1:], "\x00")) > 0 {
+		return nil, fmt.Errorf(errInvalidPadding)
+	}
+
+	return verify(padded[:end])
+
+}
+
+// ExtendPrime returns a new part of SplitPrime with the given x-coordinate from at least threshold consistent parts
+func ExtendPrime(shps [][]byte, x uint32) ([]byte, error) {
+
+	if x == 0 {
+		return nil, fmt.Errorf(errInvalidX, x)
+	}
+
+	for _, shp := range shps {
+
+		if XPrime(shp) == int(x) {
+			return nil, fmt.Errorf(errDuplicateX, x)
+		}
+
+	}
+
+	shp, err := interpolatePrime(shps, new(big.Int).SetUint64(uint64(x)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return binary.BigEndian.AppendUint32(shp, x), nil
+
+}
+
+// SplitPrime splits the given secret into parts over the prime field of the P-256 scalars, which allows more than 255 parts - the secret and its hash are padded and split into blocks, each with its own random polynomial
+func SplitPrime(s []byte, parts, threshold int) ([][]byte, error) {
+
+	if threshold < 2 || parts < threshold || uint64(parts) > 1<<32-1 {
+		return nil, fmt.Errorf(errInvalidParameters, threshold, parts)
+	}
+
+	if Debug != nil {
+		Debug("splitting s %x into %d parts with a threshold of %d over a prime field", s, parts, threshold)
+	}
+
+	padded := append(append(append([]byte(nil), s...), hash(s)...), 0x80)
+
+	for len(padded)%blockSize != 0 {
+		padded = append(padded, 0)
+	}
+
+	shps := make([][]byte, parts)
+
+	for i := range shps {
+		shps[i] = make([]byte, 0, len(padded)/blockSize*elementSize+xSize)
+	}
+
+	coefficients := make([]*big.Int, threshold)
+
+	for i := 0; i < len(padded); i += blockSize {
+
+		coefficients[0] = new(big.Int).SetBytes(padded[i : i+blockSize])
+
+		for j := 1; j < threshold; j++ {
+
+			c, err := rand.Int(rand.Reader, prime)
+
+			if err != nil {
+				return nil, errors.Wrapf(err, errFailedToGenerate)
+			}
+
+			coefficients[j] = c
+
+		}
+
+		for idx := range shps {
+			y := evaluatePrime(coefficients, big.NewInt(int64(idx+1)))
+			shps[idx] = append(shps[idx], y.FillBytes(make([]byte, elementSize))...)
+		}
+
+	}
+
+	for idx := range shps {
+		shps[idx] = binary.BigEndian.AppendUint32(shps[idx], uint32(idx+1))
+	}
+
+	return shps, nil
+
+}
+
+// XPrime returns the x-coordinate of a part of SplitPrime
+func XPrime(shp []byte) int {
+
+	if len(shp) < xSize {
+		return 0
+	}
+
+	return int(binary.BigEndian.Uint32(shp[len(shp)-xSize:]))
+
+}
+
+// evaluatePrime evaluates the polynomial with the given coefficients at x using Horner's method
+func evaluatePrime(coefficients []*big.Int, x *big.Int) *big.Int {
+
+	y := new(big.Int)
+
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		y.Mul(y, x).Add(y, coefficients[i]).Mod(y, prime)
+	}
+
+	return y
+
+}
+
+// interpolatePrime returns the encoded elements of the polynomials through the given parts at x with Lagrange interpolation
+func interpolatePrime(shps [][]byte, x *big.Int) ([]byte, error) {
+
+	if len(shps) < 2 {
+		return nil, fmt.Errorf(errTooFewParts)
+	}
+
+	var (
+		size = len(shps[0]) - xSize
+		seen = make(map[int]bool)
+		xs   = make([]*big.Int, len(shps))
+	)
+
+	if size < elementSize || size%elementSize != 0 {
+		return nil, fmt.Errorf(errInvalidPrimeLength, len(shps[0]))
+	}
+
+	for i, shp := range shps {
+
+		if len(shp) != size+xSize {
+			return nil, fmt.Errorf(errMismatchingLengths)
+		}
+
+		xi := XPrime(shp)
+
+		if xi == 0 {
+			return nil, fmt.Errorf(errInvalidX, xi)
+		}
+
+		if seen[xi] {
+			return nil, fmt.Errorf(errDuplicateX, xi)
+		}
+
+		seen[xi] = true
+		xs[i] = big.NewInt(int64(xi))
+
+	}
+
+	basis := make([]*big.Int, len(shps))
+
+	for i := range shps {
+
+		num, den := big.NewInt(1), big.NewInt(1)
+
+		for j := range shps {
+
+			if i == j {
+				continue
+			}
+
+			num.Mul(num, new(big.Int).Sub(x, xs[j])).Mod(num, prime)
+			den.Mul(den, new(big.Int).Sub(xs[i], xs[j])).Mod(den, prime)
+
+		}
+
+		basis[i] = num.Mul(num, den.ModInverse(den, prime)).Mod(num, prime)
+
+	}
+
+	out := make([]byte, 0, size)
+
+	for idx := 0; idx < size; idx += elementSize {
+
+		y := new(big.Int)
+
+		for i, shp := range shps {
+			term := new(big.Int).SetBytes(shp[idx : idx+elementSize])
+			y.Add(y, term.Mul(term, basis[i])).Mod(y, prime)
+		}
+
+		out = append(out, y.FillBytes(make([]byte, elementSize))...)
+
+	}
+
+	return out, nil
+
+}
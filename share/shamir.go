@@ -0,0 +1,142 @@
+package share
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errDuplicateX         = "duplicate x-coordinate %d"
+	errEmptySecret        = "cannot split an empty secret"
+	errFailedToGenerate   = "failed to generate polynomial"
+	errInvalidParameters  = "invalid parameters: threshold %d, parts %d"
+	errInvalidX           = "invalid x-coordinate %d"
+	errMismatchingLengths = "all parts must be of the same length"
+	errTooFewParts        = "at least two parts of at least two bytes are required"
+)
+
+// split splits the secret with one random polynomial of degree threshold-1 per byte into parts shares of the form {y1, ..., yN, x} with the x-coordinates 1 to parts, which is the encoding of hashicorp/vault/shamir
+func split(secret []byte, parts, threshold int) ([][]byte, error) {
+
+	if threshold < 2 || parts < threshold || parts > 255 {
+		return nil, fmt.Errorf(errInvalidParameters, threshold, parts)
+	}
+
+	if len(secret) == 0 {
+		return nil, fmt.Errorf(errEmptySecret)
+	}
+
+	shps := make([][]byte, parts)
+
+	for i := range shps {
+		shps[i] = make([]byte, len(secret)+1)
+		shps[i][len(secret)] = uint8(i + 1)
+	}
+
+	coefficients := make([]byte, threshold)
+
+	for idx, val := range secret {
+
+		coefficients[0] = val
+
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, errors.Wrapf(err, errFailedToGenerate)
+		}
+
+		for _, shp := range shps {
+			shp[idx] = evaluate(coefficients, shp[len(secret)])
+		}
+
+	}
+
+	for i := range coefficients {
+		coefficients[i] = 0
+	}
+
+	return shps, nil
+
+}
+
+// combine interpolates the shares at zero, which returns the secret
+func combine(shps [][]byte) ([]byte, error) {
+	return interpolate(shps, 0)
+}
+
+// evaluate evaluates the polynomial with the given coefficients at x using Horner's method
+func evaluate(coefficients []byte, x uint8) uint8 {
+
+	var y uint8
+
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		y = add(mul(y, x), coefficients[i])
+	}
+
+	return y
+
+}
+
+// interpolate returns the bytes of the polynomials through the given shares at x with Lagrange interpolation
+func interpolate(shps [][]byte, x uint8) ([]byte, error) {
+
+	if len(shps) < 2 || len(shps[0]) < 2 {
+		return nil, fmt.Errorf(errTooFewParts)
+	}
+
+	var (
+		size = len(shps[0]) - 1
+		seen = make(map[uint8]bool)
+		xs   = make([]uint8, len(shps))
+	)
+
+	for i, shp := range shps {
+
+		if len(shp) != size+1 {
+			return nil, fmt.Errorf(errMismatchingLengths)
+		}
+
+		xs[i] = shp[size]
+
+		if xs[i] == 0 {
+			return nil, fmt.Errorf(errInvalidX, xs[i])
+		}
+
+		if seen[xs[i]] {
+			return nil, fmt.Errorf(errDuplicateX, xs[i])
+		}
+
+		seen[xs[i]] = true
+
+	}
+
+	// the basis polynomials only depend on the public x-coordinates
+	basis := make([]uint8, len(shps))
+
+	for i := range shps {
+
+		basis[i] = 1
+
+		for j := range shps {
+
+			if i != j {
+				basis[i] = mul(basis[i], div(add(x, xs[j]), add(xs[i], xs[j])))
+			}
+
+		}
+
+	}
+
+	out := make([]byte, size)
+
+	for idx := range out {
+
+		for i, shp := range shps {
+			out[idx] = add(out[idx], mul(shp[idx], basis[i]))
+		}
+
+	}
+
+	return out, nil
+
+}
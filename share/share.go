@@ -1,11 +1,10 @@
-// Package share implements shamir secret sharing with a SHA3-256 hash appended to the original secret to identify a successful reconstruction
+// Package share implements shamir secret sharing over GF(256) with a SHA3-256 hash appended to the original secret to identify a successful reconstruction
 package share
 
 import (
 	"bytes"
 	"fmt"
 
-	"github.com/hashicorp/vault/shamir"
 	"golang.org/x/crypto/sha3"
 )
 
@@ -13,6 +12,9 @@ var Debug func(string, ...interface{})
 
 const errInvalidHash = "invalid hash, parts are missing"
 
+// hashSize is the size of the hash appended to the secret
+const hashSize = 32
+
 // Combine attempts to combine the given parts
 func Combine(shps [][]byte) ([]byte, error) {
 
@@ -25,26 +27,13 @@ func Combine(shps [][]byte) ([]byte, error) {
 
 	}
 
-	data, err := shamir.Combine(shps)
+	data, err := combine(shps)
 
 	if err != nil {
 		return nil, err
 	}
 
-	var (
-		p  = data[0 : len(data)-32]
-		sh = data[len(data)-32:]
-	)
-
-	if Debug != nil {
-		Debug("data %x, hash %x", p, sh)
-	}
-
-	if !bytes.Equal(hash(p), sh) {
-		return nil, fmt.Errorf(errInvalidHash)
-	}
-
-	return p, nil
+	return verify(data)
 
 }
 
@@ -57,7 +46,7 @@ func Split(s []byte, parts, threshold int) ([][]byte, error) {
 
 	sh := append(s, hash(s)...)
 
-	shps, err := shamir.Split(sh, parts, threshold)
+	shps, err := split(sh, parts, threshold)
 
 	if err != nil {
 		return nil, err
@@ -75,6 +64,66 @@ func Split(s []byte, parts, threshold int) ([][]byte, error) {
 
 }
 
+// Extend returns a new part with the given x-coordinate from at least threshold consistent parts, which extends the existing parts by another holder without changing them
+func Extend(shps [][]byte, x uint8) ([]byte, error) {
+
+	if x == 0 {
+		return nil, fmt.Errorf(errInvalidX, x)
+	}
+
+	for _, shp := range shps {
+
+		if len(shp) > 0 && shp[len(shp)-1] == x {
+			return nil, fmt.Errorf(errDuplicateX, x)
+		}
+
+	}
+
+	shp, err := interpolate(shps, x)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(shp, x), nil
+
+}
+
+// X returns the x-coordinate of a part
+func X(shp []byte) int {
+
+	if len(shp) == 0 {
+		return 0
+	}
+
+	return int(shp[len(shp)-1])
+
+}
+
+// verify splits the combined data into the secret and its hash and verifies the hash
+func verify(data []byte) ([]byte, error) {
+
+	if len(data) < hashSize {
+		return nil, fmt.Errorf(errInvalidHash)
+	}
+
+	var (
+		p  = data[0 : len(data)-hashSize]
+		sh = data[len(data)-hashSize:]
+	)
+
+	if Debug != nil {
+		Debug("data %x, hash %x", p, sh)
+	}
+
+	if !bytes.Equal(hash(p), sh) {
+		return nil, fmt.Errorf(errInvalidHash)
+	}
+
+	return p, nil
+
+}
+
 func hash(s []byte) []byte {
 
 	out := sha3.Sum256(s)
@@ -1,6 +1,7 @@
 package share
 
 import (
+	"encoding/hex"
 	"fmt"
 	"testing"
 
@@ -37,3 +38,139 @@ func TestSplitAndCombine(t *testing.T) {
 	assert.Equal("my secret", string(res))
 
 }
+
+func TestCombineVault(t *testing.T) {
+
+	assert := assert.New(t)
+
+	// parts of "my secret" created by hashicorp/vault/shamir with random x-coordinates
+	var parts [][]byte
+
+	for _, part := range []string{
+		"495dface6a6ad15ecabe804a8124b0b6ded2d5dd8e0b91787e89e9e63cf460390f135aca9ef66f0547a8",
+		"1f0ba9432df2d2d8a5c5fccfa86cb60979a4e7dddbeaf07309002aa3f57aee84147254392ebd86aa388f",
+		"beaa32b35e11c4a71d957ba698519a6faf5771dd6cb4fdfc0131cb3d609c080fb27fe1a3069641e5a90a",
+	} {
+
+		p, err := hex.DecodeString(part)
+
+		assert.NoError(err)
+
+		parts = append(parts, p)
+
+	}
+
+	res, err := Combine(parts[1:])
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(res))
+
+	// old parts can be extended as well
+	part, err := Extend(parts[:2], 1)
+
+	assert.NoError(err)
+
+	res, err = Combine([][]byte{part, parts[2]})
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(res))
+
+}
+
+func TestExtend(t *testing.T) {
+
+	assert := assert.New(t)
+
+	parts, err := Split([]byte("my secret"), 3, 2)
+
+	assert.NoError(err)
+
+	for idx, part := range parts {
+		assert.Equal(idx+1, X(part))
+	}
+
+	part, err := Extend(parts[1:], 4)
+
+	assert.NoError(err)
+	assert.Equal(4, X(part))
+
+	res, err := Combine([][]byte{parts[0], part})
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(res))
+
+	_, err = Extend(parts, 3)
+
+	assert.EqualError(err, "duplicate x-coordinate 3")
+
+	_, err = Extend(parts, 0)
+
+	assert.EqualError(err, "invalid x-coordinate 0")
+
+}
+
+func TestGF256(t *testing.T) {
+
+	assert := assert.New(t)
+
+	// FIPS 197, section 4.2
+	assert.Equal(uint8(0xc1), mul(0x57, 0x83))
+	assert.Equal(uint8(0xfe), mul(0x57, 0x13))
+
+	assert.Equal(uint8(0), inv(0))
+
+	for a := 1; a < 256; a++ {
+		assert.Equal(uint8(1), mul(uint8(a), inv(uint8(a))))
+	}
+
+}
+
+func TestSplitAndCombinePrime(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for _, secret := range []string{"", "my secret", "a secret longer than a single block of the prime field"} {
+
+		parts, err := SplitPrime([]byte(secret), 300, 3)
+
+		assert.NoError(err)
+		assert.Len(parts, 300)
+		assert.Equal(300, XPrime(parts[299]))
+
+		_, err = CombinePrime(parts[:2])
+
+		assert.Error(err)
+
+		res, err := CombinePrime([][]byte{parts[299], parts[0], parts[150]})
+
+		assert.NoError(err)
+		assert.Equal(secret, string(res))
+
+		part, err := ExtendPrime(parts[10:13], 1000)
+
+		assert.NoError(err)
+		assert.Equal(1000, XPrime(part))
+
+		res, err = CombinePrime([][]byte{part, parts[0], parts[1]})
+
+		assert.NoError(err)
+		assert.Equal(secret, string(res))
+
+	}
+
+	// a corrupted part changes every block
+	parts, err := SplitPrime([]byte("my secret"), 3, 2)
+
+	assert.NoError(err)
+
+	parts[0][0] ^= 1
+
+	_, err = CombinePrime(parts[:2])
+
+	assert.Error(err)
+
+	_, err = SplitPrime([]byte("my secret"), 1, 2)
+
+	assert.EqualError(err, "invalid parameters: threshold 2, parts 1")
+
+}
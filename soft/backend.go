@@ -0,0 +1,237 @@
+package soft
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/kreuzwerker/yess/device"
+	"github.com/pkg/errors"
+)
+
+const (
+	errFailedToParseCertificate = "failed to parse certificate in %q"
+	errFailedToParseKey         = "failed to parse private key in %q"
+	errFailedToParseSerial      = "failed to parse serial header in %q"
+	errFailedToReadKeyFile      = "failed to read key file %q"
+	errMissingKey               = "no private key found in %q"
+	errNotConnected             = "device %d is not connected"
+	errNoDevices                = "no software devices configured"
+	errUnexpectedBlock          = "unexpected PEM block %q in %q"
+)
+
+const (
+	headerPIN    = "PIN"
+	headerSerial = "Serial"
+	headerSlot   = "Slot"
+)
+
+// Backend emulates the sequential insertion of software devices - every call to Open connects to the next device. With Hub set, all devices are connected simultaneously instead.
+type Backend struct {
+	Hub     bool
+	devices []*Soft
+	next    int
+}
+
+// NewBackend returns a backend for the given devices
+func NewBackend(devices ...*Soft) *Backend {
+
+	return &Backend{
+		devices: devices,
+	}
+
+}
+
+// Load returns a backend for the software devices stored in the given key files. Every key file contains a PEM encoded EC or RSA private key with optional "Serial", "Slot" and "PIN" headers and an optional certificate.
+func Load(files ...string) (*Backend, error) {
+
+	var devices []*Soft
+
+	for _, file := range files {
+
+		d, err := load(file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		devices = append(devices, d)
+
+	}
+
+	return NewBackend(devices...), nil
+
+}
+
+// Connect connects to the listed device, if it is connected, and logs in with the given PIN
+func (b *Backend) Connect(info *device.Info, pin string) (device.Device, error) {
+
+	for _, d := range b.connected() {
+
+		if d.Serial() != info.Serial {
+			continue
+		}
+
+		if !b.Hub {
+			b.next++
+		}
+
+		if err := d.Login(pin); err != nil {
+			return nil, err
+		}
+
+		return d, nil
+
+	}
+
+	return nil, fmt.Errorf(errNotConnected, info.Serial)
+
+}
+
+// Devices lists the connected software devices - the next device or, with Hub set, all devices
+func (b *Backend) Devices() ([]*device.Info, error) {
+
+	var infos []*device.Info
+
+	for _, d := range b.connected() {
+
+		version, _ := d.Version()
+
+		infos = append(infos, &device.Info{
+			Reader:  d.Vendor(),
+			Serial:  d.Serial(),
+			Version: version,
+		})
+
+	}
+
+	return infos, nil
+
+}
+
+// Open connects to the next device and logs in with the given PIN
+func (b *Backend) Open(pin string) (device.Device, error) {
+
+	if len(b.devices) == 0 {
+		return nil, fmt.Errorf(errNoDevices)
+	}
+
+	d := b.devices[b.next%len(b.devices)]
+
+	b.next++
+
+	if err := d.Login(pin); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+
+}
+
+// connected returns the currently connected devices
+func (b *Backend) connected() []*Soft {
+
+	if b.Hub || len(b.devices) == 0 {
+		return b.devices
+	}
+
+	return []*Soft{b.devices[b.next%len(b.devices)]}
+
+}
+
+// load loads a single software device from a key file
+func load(file string) (*Soft, error) {
+
+	in, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToReadKeyFile, file)
+	}
+
+	var (
+		cert   *x509.Certificate
+		key    crypto.PrivateKey
+		pin    = DefaultPIN
+		serial uint64
+		slot   = device.DefaultSlot
+	)
+
+	for {
+
+		var block *pem.Block
+
+		block, in = pem.Decode(in)
+
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+
+		case "CERTIFICATE":
+
+			if cert, err = x509.ParseCertificate(block.Bytes); err != nil {
+				return nil, errors.Wrapf(err, errFailedToParseCertificate, file)
+			}
+
+		case "EC PRIVATE KEY", "PRIVATE KEY", "RSA PRIVATE KEY":
+
+			switch block.Type {
+			case "EC PRIVATE KEY":
+				key, err = x509.ParseECPrivateKey(block.Bytes)
+			case "RSA PRIVATE KEY":
+				key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+			default:
+				key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+			}
+
+			if err != nil {
+				return nil, errors.Wrapf(err, errFailedToParseKey, file)
+			}
+
+			if value, ok := block.Headers[headerPIN]; ok {
+				pin = value
+			}
+
+			if value, ok := block.Headers[headerSlot]; ok {
+				slot = value
+			}
+
+			if value, ok := block.Headers[headerSerial]; ok {
+
+				if serial, err = strconv.ParseUint(value, 10, 32); err != nil {
+					return nil, errors.Wrapf(err, errFailedToParseSerial, file)
+				}
+
+			}
+
+		case "EC PARAMETERS":
+			// emitted by "openssl ecparam -genkey"
+
+		default:
+			return nil, fmt.Errorf(errUnexpectedBlock, block.Type, file)
+
+		}
+
+	}
+
+	if key == nil {
+		return nil, fmt.Errorf(errMissingKey, file)
+	}
+
+	soft, err := New(key, cert, uint32(serial), pin)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := soft.SetSlot(slot); err != nil {
+		return nil, err
+	}
+
+	return soft, nil
+
+}
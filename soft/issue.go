@@ -0,0 +1,94 @@
+package soft
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// certificate mirrors the ASN.1 structure of a certificate
+type certificate struct {
+	TBSCertificate     asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+// tbsCertificate mirrors the ASN.1 structure of the signed part of a certificate
+type tbsCertificate struct {
+	Version            int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           asn1.RawValue
+	Subject            asn1.RawValue
+	PublicKey          asn1.RawValue
+	Extensions         []pkix.Extension `asn1:"omitempty,optional,explicit,tag:3"`
+}
+
+// issue creates a certificate for a public key the x509 package cannot create certificates for (e.g. X25519) by creating it for a placeholder key, replacing the subject public key info and signing it again with a throwaway Ed25519 issuer
+func issue(template *x509.Certificate, pub crypto.PublicKey) (*x509.Certificate, error) {
+
+	ipk, isk, err := ed25519.GenerateKey(rand.Reader)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToCreateCertificate)
+	}
+
+	issuer := &x509.Certificate{
+		SerialNumber: template.SerialNumber,
+		Subject: pkix.Name{
+			CommonName: "yess soft device issuer",
+		},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, ipk, isk)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToCreateCertificate)
+	}
+
+	var (
+		cert certificate
+		tbs  tbsCertificate
+	)
+
+	if _, err := asn1.Unmarshal(der, &cert); err != nil {
+		return nil, errors.Wrapf(err, errFailedToCreateCertificate)
+	}
+
+	if _, err := asn1.Unmarshal(cert.TBSCertificate.FullBytes, &tbs); err != nil {
+		return nil, errors.Wrapf(err, errFailedToCreateCertificate)
+	}
+
+	spki, err := x509.MarshalPKIXPublicKey(pub)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToCreateCertificate)
+	}
+
+	tbs.PublicKey = asn1.RawValue{FullBytes: spki}
+
+	raw, err := asn1.Marshal(tbs)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToCreateCertificate)
+	}
+
+	sig := ed25519.Sign(isk, raw)
+
+	cert.TBSCertificate = asn1.RawValue{FullBytes: raw}
+	cert.SignatureValue = asn1.BitString{Bytes: sig, BitLength: len(sig) * 8}
+
+	if der, err = asn1.Marshal(cert); err != nil {
+		return nil, errors.Wrapf(err, errFailedToCreateCertificate)
+	}
+
+	return x509.ParseCertificate(der)
+
+}
@@ -0,0 +1,344 @@
+// Package soft implements an in-process software emulation of the PIV key management slot of a Yubikey for tests and dry runs
+package soft
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/kreuzwerker/yess/device"
+	"github.com/kreuzwerker/yess/result"
+	"github.com/pkg/errors"
+)
+
+const (
+	errFailedToCreateCertificate = "failed to create self-signed certificate"
+	errFailedToGenerateKey       = "failed to generate key"
+	errInvalidPoint              = "invalid point for curve %s"
+	errNotLoggedIn               = "not logged in"
+	errNoCertificate             = "no certificate present in slot %s of device %d"
+	errUnsupportedKey            = "unsupported key type %T"
+)
+
+const (
+	// DefaultPIN is the factory default PIN of a Yubikey
+	DefaultPIN = "123456"
+	// Retries is the factory default number of PIN retries of a Yubikey
+	Retries = 3
+)
+
+// Soft represents a software device with a key management slot
+type Soft struct {
+	cert     *x509.Certificate
+	key      privateKey
+	loggedIn bool
+	pin      string
+	retries  int
+	serial   uint32
+	slot     string
+}
+
+// privateKey is implemented by all supported private keys
+type privateKey interface {
+	Public() crypto.PublicKey
+}
+
+// Generate creates a software device with a fresh key on the given curve
+func Generate(curve elliptic.Curve, serial uint32, pin string) (*Soft, error) {
+
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToGenerateKey)
+	}
+
+	return New(key, nil, serial, pin)
+
+}
+
+// GenerateRSA creates a software device with a fresh RSA key of the given size
+func GenerateRSA(bits int, serial uint32, pin string) (*Soft, error) {
+
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToGenerateKey)
+	}
+
+	return New(key, nil, serial, pin)
+
+}
+
+// GenerateX25519 creates a software device with a fresh X25519 key
+func GenerateX25519(serial uint32, pin string) (*Soft, error) {
+
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToGenerateKey)
+	}
+
+	return New(key, nil, serial, pin)
+
+}
+
+// New creates a software device from a private key and an optional certificate. If the certificate is nil, a self-signed certificate (or for X25519 keys a certificate signed by a throwaway issuer) is created. If the serial is zero, it is derived from the public key.
+func New(key crypto.PrivateKey, cert *x509.Certificate, serial uint32, pin string) (*Soft, error) {
+
+	var pk privateKey
+
+	switch t := key.(type) {
+	case *ecdsa.PrivateKey, *rsa.PrivateKey:
+		pk = t.(privateKey)
+	case *ecdh.PrivateKey:
+
+		if t.Curve() != ecdh.X25519() {
+			return nil, fmt.Errorf(errUnsupportedKey, key)
+		}
+
+		pk = t
+
+	default:
+		return nil, fmt.Errorf(errUnsupportedKey, key)
+	}
+
+	if serial == 0 {
+
+		der, err := x509.MarshalPKIXPublicKey(pk.Public())
+
+		if err != nil {
+			return nil, err
+		}
+
+		serial = device.DeriveSerial(der)
+
+	}
+
+	if cert == nil {
+
+		var err error
+
+		cert, err = selfSign(pk, serial)
+
+		if err != nil {
+			return nil, err
+		}
+
+	}
+
+	return &Soft{
+		cert:    cert,
+		key:     pk,
+		pin:     pin,
+		retries: Retries,
+		serial:  serial,
+		slot:    device.DefaultSlot,
+	}, nil
+
+}
+
+// Certificate returns the certificate of the key management slot
+func (s *Soft) Certificate() *x509.Certificate {
+	return s.cert
+}
+
+// Close logs out of the device
+func (s *Soft) Close() error {
+
+	s.loggedIn = false
+
+	return nil
+
+}
+
+// Decrypt decrypts a given part of a result, yielding the plaintext share
+func (s *Soft) Decrypt(res *result.Result, p *result.Part) ([]byte, error) {
+
+	if !s.loggedIn {
+		return nil, fmt.Errorf(errNotLoggedIn)
+	}
+
+	if slot := p.Slot; slot != s.slot && !(slot == "" && s.slot == device.DefaultSlot) {
+		return nil, fmt.Errorf(errNoCertificate, slot, s.serial)
+	}
+
+	return device.Decrypt(&slot{s.key}, res, p)
+
+}
+
+// Encrypt encrypts the given share of a result into the part with the given index
+func (s *Soft) Encrypt(res *result.Result, index int, msg []byte) (*result.Part, error) {
+	return device.Encrypt(s, res, index, msg)
+}
+
+// Login verifies the PIN, decrementing the retry counter on failure and locking the device when no retries remain
+func (s *Soft) Login(pin string) error {
+
+	if s.retries == 0 {
+		return &device.PINError{Serial: s.serial}
+	}
+
+	if pin != s.pin {
+
+		s.retries--
+
+		return &device.PINError{
+			Retries: s.retries,
+			Serial:  s.serial,
+		}
+
+	}
+
+	s.loggedIn = true
+	s.retries = Retries
+
+	return nil
+
+}
+
+// Retries returns the number of remaining PIN retries
+func (s *Soft) Retries() int {
+	return s.retries
+}
+
+// Serial returns the serial number of the device
+func (s *Soft) Serial() uint32 {
+	return s.serial
+}
+
+// SetSlot moves the key to the slot with the given hex name
+func (s *Soft) SetSlot(slot string) error {
+
+	if _, err := device.ParseSlot(slot); err != nil {
+		return err
+	}
+
+	s.slot = slot
+
+	return nil
+
+}
+
+// Slot returns the hex name of the slot holding the key
+func (s *Soft) Slot() string {
+	return s.slot
+}
+
+// Version returns the firmware version of the device
+func (s *Soft) Version() (string, error) {
+	return "soft", nil
+}
+
+// Vendor returns the vendor string of the device, which doubles as its reader name
+func (s *Soft) Vendor() string {
+	return fmt.Sprintf("Soft Device %d", s.serial)
+}
+
+// slot emulates the decipher operation of a PIV slot
+type slot struct {
+	key privateKey
+}
+
+// Decrypt performs ECDH with the ANSI X9.62 encoded peer key, yielding the fixed-length x-coordinate like a Yubikey does, X25519 with the raw peer key, or RSA decryption with the removal of PKCS #1 v1.5 padding like the PIV library does
+func (s *slot) Decrypt(_ io.Reader, msg []byte, _ crypto.DecrypterOpts) ([]byte, error) {
+
+	switch key := s.key.(type) {
+
+	case *ecdsa.PrivateKey:
+
+		curve := key.Curve
+
+		x, y := elliptic.Unmarshal(curve, msg)
+
+		if x == nil {
+			return nil, fmt.Errorf(errInvalidPoint, curve.Params().Name)
+		}
+
+		sk, _ := curve.ScalarMult(x, y, key.D.Bytes())
+
+		out := make([]byte, (curve.Params().BitSize+7)/8)
+
+		return sk.FillBytes(out), nil
+
+	case *ecdh.PrivateKey:
+
+		pk, err := key.Curve().NewPublicKey(msg)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return key.ECDH(pk)
+
+	case *rsa.PrivateKey:
+		return rsa.DecryptPKCS1v15(nil, key, msg)
+
+	default:
+		return nil, fmt.Errorf(errUnsupportedKey, key)
+
+	}
+
+}
+
+// Public returns the public key of the slot
+func (s *slot) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+// selfSign creates a self-signed key management certificate like the one created by the Yubikey tooling, carrying the device serial in the extension of attestation certificates
+func selfSign(key privateKey, serial uint32) (*x509.Certificate, error) {
+
+	now := time.Now().UTC().Truncate(time.Second)
+
+	ext, err := asn1.Marshal(int64(serial))
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToCreateCertificate)
+	}
+
+	// like on Yubikeys, the certificate serial number is unrelated to the device serial
+	sn, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToCreateCertificate)
+	}
+
+	template := &x509.Certificate{
+		ExtraExtensions: []pkix.Extension{
+			{Id: device.OIDSerial, Value: ext},
+		},
+		KeyUsage:     x509.KeyUsageKeyAgreement,
+		NotAfter:     now.AddDate(1, 0, 0),
+		NotBefore:    now,
+		SerialNumber: sn.Add(sn, big.NewInt(1)),
+		Subject: pkix.Name{
+			CommonName: fmt.Sprintf("yess soft device %d", serial),
+		},
+	}
+
+	signer, ok := key.(crypto.Signer)
+
+	// X25519 keys cannot sign their own certificate
+	if !ok {
+		return issue(template, key.Public())
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), signer)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToCreateCertificate)
+	}
+
+	return x509.ParseCertificate(der)
+
+}
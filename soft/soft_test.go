@@ -0,0 +1,192 @@
+package soft
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kreuzwerker/yess/encrypt"
+	"github.com/kreuzwerker/yess/result"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+
+	assert := assert.New(t)
+
+	for name, generate := range map[string]func() (*Soft, error){
+		"ecdh": func() (*Soft, error) {
+			return Generate(elliptic.P256(), 1, DefaultPIN)
+		},
+		"x25519": func() (*Soft, error) {
+			return GenerateX25519(1, DefaultPIN)
+		},
+		"rsa-pkcs1v15": func() (*Soft, error) {
+			return GenerateRSA(2048, 1, DefaultPIN)
+		},
+	} {
+
+		soft, err := generate()
+
+		assert.NoError(err)
+
+		res := &result.Result{ID: "a"}
+
+		part, err := soft.Encrypt(res, 1, []byte("my share"))
+
+		assert.NoError(err)
+		assert.Equal(name, part.Algorithm.KeyAgreement)
+		assert.Equal(uint32(1), part.Serial)
+		assert.Equal("Soft Device 1", part.Device)
+		assert.Equal("CN=yess soft device 1", part.Subject)
+
+		_, err = soft.Decrypt(res, part)
+
+		assert.EqualError(err, "not logged in")
+
+		assert.NoError(soft.Login(DefaultPIN))
+
+		share, err := soft.Decrypt(res, part)
+
+		assert.NoError(err)
+		assert.Equal("my share", string(share))
+
+		// the key is bound to the result
+		_, err = soft.Decrypt(&result.Result{ID: "b"}, part)
+
+		assert.EqualError(err, "failed to authenticate share - wrong key or tampered result")
+
+	}
+
+}
+
+func TestDecryptV1(t *testing.T) {
+
+	assert := assert.New(t)
+
+	soft, err := Generate(elliptic.P256(), 1, DefaultPIN)
+
+	assert.NoError(err)
+	assert.NoError(soft.Login(DefaultPIN))
+
+	dkp := soft.Certificate().PublicKey.(*ecdsa.PublicKey)
+
+	// version 1 used the minimal encoding of the x-coordinate, which differs from the device output for leading zeros
+	for {
+
+		eks, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+		assert.NoError(err)
+
+		x, _ := elliptic.P256().ScalarMult(dkp.X, dkp.Y, eks.D.Bytes())
+
+		if len(x.Bytes()) == 32 {
+			continue
+		}
+
+		part := &result.Part{
+			Algorithm: result.Algorithm{
+				Cipher:       result.CipherSecretbox,
+				KDF:          result.KDFSHA3,
+				KeyAgreement: result.KeyAgreementECDH,
+			},
+			Share: encrypt.Encrypt(x.Bytes(), []byte("my share")),
+		}
+
+		assert.NoError(part.AddKey(&eks.PublicKey))
+
+		share, err := soft.Decrypt(&result.Result{}, part)
+
+		assert.NoError(err)
+		assert.Equal("my share", string(share))
+
+		break
+
+	}
+
+}
+
+func TestLogin(t *testing.T) {
+
+	assert := assert.New(t)
+
+	soft, err := Generate(elliptic.P256(), 1, "654321")
+
+	assert.NoError(err)
+
+	assert.EqualError(soft.Login(DefaultPIN), "wrong PIN for device 1 (2 retries remaining)")
+	assert.EqualError(soft.Login(DefaultPIN), "wrong PIN for device 1 (1 retries remaining)")
+
+	assert.NoError(soft.Login("654321"))
+	assert.Equal(Retries, soft.Retries())
+
+	for i := 0; i < Retries; i++ {
+		assert.Error(soft.Login(DefaultPIN))
+	}
+
+	assert.EqualError(soft.Login("654321"), "PIN of device 1 is locked")
+	assert.Equal(0, soft.Retries())
+
+}
+
+func TestLoad(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "yess")
+
+	assert.NoError(err)
+
+	defer os.RemoveAll(dir)
+
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+	assert.NoError(err)
+
+	der, err := x509.MarshalECPrivateKey(key)
+
+	assert.NoError(err)
+
+	a := filepath.Join(dir, "a.pem")
+
+	assert.NoError(ioutil.WriteFile(a, pem.EncodeToMemory(&pem.Block{
+		Type:  "EC PRIVATE KEY",
+		Bytes: der,
+		Headers: map[string]string{
+			"PIN":    "000000",
+			"Serial": "42",
+		},
+	}), 0600))
+
+	b := filepath.Join(dir, "b.pem")
+
+	assert.NoError(ioutil.WriteFile(b, pem.EncodeToMemory(&pem.Block{
+		Type:  "EC PRIVATE KEY",
+		Bytes: der,
+	}), 0600))
+
+	backend, err := Load(a, b)
+
+	assert.NoError(err)
+
+	d, err := backend.Open("000000")
+
+	assert.NoError(err)
+	assert.Equal(uint32(42), d.Serial())
+
+	d, err = backend.Open(DefaultPIN)
+
+	assert.NoError(err)
+	assert.NotEqual(uint32(0), d.Serial())
+
+	_, err = Load(filepath.Join(dir, "c.pem"))
+
+	assert.Error(err)
+
+}
@@ -0,0 +1,49 @@
+package split
+
+import (
+	"github.com/kreuzwerker/yess/device"
+	"github.com/kreuzwerker/yess/result"
+	"github.com/pkg/errors"
+)
+
+const (
+	errFailedToEncryptBackup = "failed to encrypt share to backup device %d"
+	logBackupAdded           = "encrypted part of device %d to backup device %d"
+)
+
+// backup encrypts the plaintext of the part to the backup devices of its holder - the stanzas share the index, x-coordinate and weight of the part, so either device counts as the same holder
+func (s *Split) backup(res *result.Result, part *result.Part, plaintext []byte) error {
+
+	for _, backup := range s.Backups[part.Serial] {
+
+		stanza, err := backup.Encrypt(res, part.Index, plaintext)
+
+		if err != nil {
+			return errors.Wrapf(err, errFailedToEncryptBackup, backup.Serial())
+		}
+
+		stanza.Weight = part.Weight
+		stanza.X = part.X
+
+		part.Backups = append(part.Backups, stanza)
+
+		s.out(logBackupAdded, part.Serial, stanza.Serial)
+
+	}
+
+	return nil
+
+}
+
+// serials returns the serial of the recipient followed by the serials of its backup devices
+func (s *Split) serials(r device.Recipient) []uint32 {
+
+	serials := []uint32{r.Serial()}
+
+	for _, backup := range s.Backups[r.Serial()] {
+		serials = append(serials, backup.Serial())
+	}
+
+	return serials
+
+}
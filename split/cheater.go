@@ -0,0 +1,118 @@
+package split
+
+import (
+	"bytes"
+
+	"github.com/kreuzwerker/yess/result"
+)
+
+const logInconsistentPart = "part of device %d (subject %s) is inconsistent with the recovered secret - it has been corrupted or issued maliciously"
+
+// reconstruct attempts to recover the secret from every subset of threshold shares that contains at least one of the given number of latest shares, since subsets without them have already been tried - it returns the combined data and the parts whose shares are inconsistent with it
+func reconstruct(res *result.Result, shares [][]byte, parts []*result.Part, latest int) ([]byte, []*result.Part, error) {
+
+	var (
+		combined []byte
+		err      error
+		first    = len(shares) - latest
+		subset   []int
+	)
+
+	subsets(len(shares), res.Threshold, func(indices []int) bool {
+
+		// the indices are sorted, so the last one tells whether any of the latest shares is included
+		if indices[len(indices)-1] < first {
+			return false
+		}
+
+		subset = indices
+
+		combined, err = combine(res, pick(shares, subset))
+
+		return err == nil
+
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		cheaters []*result.Part
+		chosen   = make(map[int]bool)
+	)
+
+	for _, idx := range subset {
+		chosen[idx] = true
+	}
+
+	// t consistent shares determine the polynomial, so replacing one of them with a share off the polynomial changes the secret
+	for idx := range shares {
+
+		if chosen[idx] {
+			continue
+		}
+
+		candidate, err := combine(res, pick(shares, append([]int{idx}, subset[1:]...)))
+
+		if err != nil || !bytes.Equal(candidate, combined) {
+			cheaters = append(cheaters, parts[idx])
+		}
+
+	}
+
+	return combined, cheaters, nil
+
+}
+
+// pick returns the shares with the given indices
+func pick(shares [][]byte, indices []int) [][]byte {
+
+	var picked [][]byte
+
+	for _, idx := range indices {
+		picked = append(picked, shares[idx])
+	}
+
+	return picked
+
+}
+
+// subsets calls fn with every subset of k indices below n in lexicographic order until fn returns true
+func subsets(n, k int, fn func([]int) bool) {
+
+	if k > n {
+		return
+	}
+
+	indices := make([]int, k)
+
+	for i := range indices {
+		indices[i] = i
+	}
+
+	for {
+
+		if fn(append([]int(nil), indices...)) {
+			return
+		}
+
+		i := k - 1
+
+		for i >= 0 && indices[i] == n-k+i {
+			i--
+		}
+
+		if i < 0 {
+			return
+		}
+
+		indices[i]++
+
+		for j := i + 1; j < k; j++ {
+			indices[j] = indices[j-1] + 1
+		}
+
+	}
+
+}
@@ -0,0 +1,226 @@
+package split
+
+import (
+	"fmt"
+
+	"github.com/kreuzwerker/yess/device"
+	"github.com/kreuzwerker/yess/result"
+	shamir "github.com/kreuzwerker/yess/share"
+	"github.com/kreuzwerker/yess/vss"
+	"github.com/pkg/errors"
+)
+
+const (
+	errMismatchingX    = "share of device %d has x-coordinate %d instead of %d"
+	errMissingHolders  = "no holders to add"
+	errMissingResultID = "result has no ID - please migrate it before adding holders"
+	errPolicyHolders   = "holders cannot be added to results with a policy"
+	errTooManyHolders  = "x-coordinate %d exceeds the field of the result"
+	errUnknownX        = "x-coordinates of %d parts are unknown - a new share may coincide with one of them and prevent the holders from combining together, so please combine with their devices or allow unknown x-coordinates"
+	logAddingHolders   = "adding %d holders after combining the result"
+	logHolderAdded     = "added part for device %d with x-coordinate %d"
+	logUnknownX        = "x-coordinates of %d parts are unknown - a new share may coincide with one of them, which does not weaken the sharing, but prevents the holders from combining together"
+)
+
+// AddHolders adds a part for each of the given recipients to the result after a combination with threshold devices - the new shares lie on the same polynomial, so the existing parts stay valid and the secret stays the same
+func (s *Split) AddHolders(res *result.Result, recipients []device.Recipient) error {
+
+	if len(recipients) == 0 {
+		return fmt.Errorf(errMissingHolders)
+	}
+
+	// the parts of new holders are authenticated with the ID
+	if res.ID == "" {
+		return fmt.Errorf(errMissingResultID)
+	}
+
+	if res.Policy != nil {
+		return fmt.Errorf(errPolicyHolders)
+	}
+
+	mapping := make(map[uint32]bool)
+
+	for _, part := range res.Parts {
+
+		for _, stanza := range part.Stanzas() {
+			mapping[stanza.Serial] = true
+		}
+
+	}
+
+	for _, recipient := range recipients {
+
+		if mapping[recipient.Serial()] {
+			return fmt.Errorf(errDuplicateDeviceUsed, recipient.Serial())
+		}
+
+		mapping[recipient.Serial()] = true
+
+	}
+
+	s.out(logAddingHolders, len(recipients))
+
+	_, shares, parts, err := s.collect(res)
+
+	if err != nil {
+		return err
+	}
+
+	learn(res, shares, parts)
+
+	var (
+		index   int
+		unknown int
+		used    = make(map[int]bool)
+	)
+
+	// parts of older results that did not take part have no recorded x-coordinate
+	for _, part := range res.Parts {
+
+		if part.Index > index {
+			index = part.Index
+		}
+
+		if part.X == 0 {
+			unknown++
+			continue
+		}
+
+		for x := part.X; x < part.X+part.Shares(); x++ {
+			used[x] = true
+		}
+
+	}
+
+	if unknown > 0 {
+
+		if !s.UnknownX {
+			return fmt.Errorf(errUnknownX, unknown)
+		}
+
+		s.out(logUnknownX, unknown)
+
+	}
+
+	// older results used random x-coordinates, so the first unused ones are taken
+	var x int
+
+	for _, recipient := range recipients {
+
+		index++
+		x++
+
+		for used[x] {
+			x++
+		}
+
+		share, err := extend(res, shares, x)
+
+		if err != nil {
+			return err
+		}
+
+		part, err := recipient.Encrypt(res, index, share)
+
+		if err != nil {
+			return errors.Wrapf(err, errFailedToEncrypt)
+		}
+
+		part.X = x
+
+		if err := s.backup(res, part, share); err != nil {
+			return err
+		}
+
+		res.Parts = append(res.Parts, part)
+
+		s.out(logHolderAdded, part.Serial, part.X)
+
+	}
+
+	return nil
+
+}
+
+// check unpacks the shares of a decrypted part and verifies them against the commitments and the recorded x-coordinates of the part, if any
+func check(res *result.Result, part *result.Part, plaintext []byte) ([][]byte, error) {
+
+	shares, err := unpack(part, plaintext)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for idx, share := range shares {
+
+		if res.Sharing == result.SharingFeldman {
+
+			if err := vss.Verify(share, res.Commitments); err != nil {
+				return nil, err
+			}
+
+		}
+
+		// the x-coordinates are authenticated as part of the shares
+		if x := coordinate(res, share); part.X != 0 && x != part.X+idx {
+			return nil, fmt.Errorf(errMismatchingX, part.Serial, x, part.X+idx)
+		}
+
+	}
+
+	return shares, nil
+
+}
+
+// learn records the x-coordinates of the parts of older results that took part in a combination
+func learn(res *result.Result, shares [][]byte, parts []*result.Part) {
+
+	for idx, part := range parts {
+
+		// the first share of weighted parts holds the lowest x-coordinate
+		if part.X != 0 {
+			continue
+		}
+
+		part.X = coordinate(res, shares[idx])
+
+		for _, backup := range part.Backups {
+			backup.X = part.X
+		}
+
+	}
+
+}
+
+// coordinate returns the x-coordinate of a share
+func coordinate(res *result.Result, share []byte) int {
+
+	switch res.Sharing {
+	case result.SharingFeldman:
+		index, _ := vss.Index(share)
+		return int(index)
+	case result.SharingShamirP256:
+		return shamir.XPrime(share)
+	}
+
+	return shamir.X(share)
+
+}
+
+// extend returns a new share with the given x-coordinate from the given consistent shares
+func extend(res *result.Result, shares [][]byte, x int) ([]byte, error) {
+
+	switch res.Sharing {
+	case result.SharingFeldman:
+		return vss.Extend(shares, res.Commitments, uint32(x))
+	case result.SharingShamirP256:
+		return shamir.ExtendPrime(shares, uint32(x))
+	}
+
+	if x > 255 {
+		return nil, fmt.Errorf(errTooManyHolders, x)
+	}
+
+	return shamir.Extend(shares, uint8(x))
+
+}
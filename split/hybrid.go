@@ -0,0 +1,139 @@
+package split
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/kreuzwerker/yess/encrypt"
+	"github.com/kreuzwerker/yess/result"
+	"github.com/kreuzwerker/yess/stream"
+	"github.com/kreuzwerker/yess/vss"
+	"github.com/pkg/errors"
+)
+
+const (
+	errFailedToDecryptPayload = "failed to decrypt payload - it has been tampered with or belongs to another result"
+	errFailedToEncryptPayload = "failed to encrypt payload"
+	errFailedToGenerateDEK    = "failed to generate DEK"
+	errFailedToStream         = "failed to stream payload"
+)
+
+const (
+	// labelPayload separates the associated data of payloads from other data
+	labelPayload = "yess v2 payload"
+	// labelStream separates the keys of streamed payloads from other keys
+	labelStream = "yess v2 stream"
+)
+
+// dekSize is the size of the data encryption key
+const dekSize = 32
+
+// Stream configures the streaming mode, which encrypts the secret from In into Out in chunks instead of storing it in the result
+type Stream struct {
+	In  io.Reader // In is the plaintext secret when splitting and the encrypted payload when combining
+	Out io.Writer // Out is the encrypted payload when splitting and the plaintext secret when combining, which receives chunks before the whole payload has been verified
+}
+
+// prepare returns the data to split for the result - in hybrid and streaming mode, the secret is encrypted with a random DEK into the payload of the result or the stream output and the DEK is returned instead. Verifiable sharing implies the hybrid mode, since only a scalar DEK can be committed to.
+func (s *Split) prepare(res *result.Result, secret []byte) ([]byte, error) {
+
+	if !s.Hybrid && !s.VSS && s.Stream == nil {
+		return secret, nil
+	}
+
+	dek, err := s.dek()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Stream != nil {
+
+		w, err := stream.NewWriter(s.Stream.Out, streamKey(res, dek))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := io.Copy(w, s.Stream.In); err != nil {
+			return nil, errors.Wrapf(err, errFailedToStream)
+		}
+
+		if err := w.Close(); err != nil {
+			return nil, errors.Wrapf(err, errFailedToStream)
+		}
+
+		res.Mode = result.ModeStream
+
+		return dek, nil
+
+	}
+
+	payload, err := encrypt.SealAEAD(dek, secret, encrypt.Concat([]byte(labelPayload), []byte(res.ID)))
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToEncryptPayload)
+	}
+
+	res.Mode = result.ModeDEK
+	res.Payload = payload
+
+	return dek, nil
+
+}
+
+// finish returns the secret from the combined data of the result - in hybrid mode, this is the DEK decrypting the payload of the result, in streaming mode the DEK decrypts the stream input into the stream output and nil is returned
+func (s *Split) finish(res *result.Result, combined []byte) ([]byte, error) {
+
+	switch res.Mode {
+	case result.ModeDEK:
+	case result.ModeStream:
+
+		r, err := stream.NewReader(s.Stream.In, streamKey(res, combined))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := io.Copy(s.Stream.Out, r); err != nil {
+			return nil, errors.Wrapf(err, errFailedToStream)
+		}
+
+		return nil, nil
+
+	default:
+		return combined, nil
+	}
+
+	secret, ok := encrypt.OpenAEAD(combined, res.Payload, encrypt.Concat([]byte(labelPayload), []byte(res.ID)))
+
+	if !ok {
+		return nil, fmt.Errorf(errFailedToDecryptPayload)
+	}
+
+	return secret, nil
+
+}
+
+// dek generates a random DEK, which is a scalar in verifiable sharing
+func (s *Split) dek() ([]byte, error) {
+
+	if s.VSS {
+		return vss.Scalar()
+	}
+
+	dek := make([]byte, dekSize)
+
+	if _, err := rand.Read(dek); err != nil {
+		return nil, errors.Wrapf(err, errFailedToGenerateDEK)
+	}
+
+	return dek, nil
+
+}
+
+// streamKey derives the key of the streamed payload of the result from the DEK
+func streamKey(res *result.Result, dek []byte) []byte {
+	return encrypt.Derive(dek, []byte(labelStream), []byte(res.ID))
+}
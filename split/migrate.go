@@ -0,0 +1,53 @@
+package split
+
+import (
+	"fmt"
+
+	"github.com/kreuzwerker/yess/result"
+)
+
+// Migrate combines a result of an older version to verify that it can still be recovered - the parts that took part record their x-coordinates and results without an ID are assigned one, which the parts of holders added later are authenticated with. The secret is discarded.
+func (s *Split) Migrate(res *result.Result) error {
+
+	if res.Mode == result.ModeDEK && len(res.Payload) == 0 {
+		return fmt.Errorf(errMissingPayload)
+	}
+
+	if res.Mode == result.ModeStream && s.Stream == nil {
+		return fmt.Errorf(errMissingStream)
+	}
+
+	combined, shares, parts, err := s.collect(res)
+
+	if err != nil {
+		return err
+	}
+
+	secret, err := s.finish(res, combined)
+
+	for _, b := range [][]byte{combined, secret} {
+
+		for i := range b {
+			b[i] = 0
+		}
+
+	}
+
+	if err != nil {
+		return err
+	}
+
+	learn(res, shares, parts)
+
+	// the parts of older results are not authenticated with an ID, so it can be assigned safely
+	if res.ID == "" {
+
+		if res.ID, err = result.NewID(); err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
@@ -0,0 +1,203 @@
+package split
+
+import (
+	"fmt"
+
+	"github.com/kreuzwerker/yess/device"
+	"github.com/kreuzwerker/yess/result"
+	"github.com/pkg/errors"
+)
+
+const (
+	errPolicyVSS           = "verifiable sharing does not support policies"
+	logGroupFailed         = "group %q has enough members, but its share cannot be recovered yet (%s)"
+	logGroupUnsatisfied    = "group %q is not satisfied yet: %d of %d required members present"
+	logSplittingWithPolicy = "splitting secret with a policy for %d recipients"
+)
+
+// Policy is a tree of groups of holders - a group is satisfied once Threshold of its members, i.e. its subgroups followed by its holders, are satisfied
+type Policy struct {
+	Groups    []*Policy          // Groups are the subgroups
+	Holders   []device.Recipient // Holders receive one part each
+	Name      string             // Name identifies the group towards the holders
+	Threshold int                // Threshold is the number of members required to satisfy the group
+}
+
+// SplitPolicy splits the secret recursively along the groups of the policy without connecting to any device - every group splits its share among its members, holders receive their share as part
+func (s *Split) SplitPolicy(secret []byte, policy *Policy) (*result.Result, error) {
+
+	if s.VSS {
+		return nil, fmt.Errorf(errPolicyVSS)
+	}
+
+	if len(s.Weights) > 0 {
+		return nil, fmt.Errorf(errPolicyWeights)
+	}
+
+	var (
+		count   int
+		mapping = make(map[uint32]bool)
+		walk    func(p *Policy) error
+	)
+
+	walk = func(p *Policy) error {
+
+		for _, holder := range p.Holders {
+
+			for _, serial := range s.serials(holder) {
+
+				if mapping[serial] {
+					return fmt.Errorf(errDuplicateDeviceUsed, serial)
+				}
+
+				mapping[serial] = true
+
+			}
+
+			count++
+
+		}
+
+		for _, group := range p.Groups {
+
+			if err := walk(group); err != nil {
+				return err
+			}
+
+		}
+
+		return nil
+
+	}
+
+	if err := walk(policy); err != nil {
+		return nil, err
+	}
+
+	result, err := result.New(policy.Threshold)
+
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := s.prepare(result, secret)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.out(logSplittingWithPolicy, count)
+
+	if result.Policy, err = s.distribute(result, policy, shared); err != nil {
+		return nil, err
+	}
+
+	return result, result.Validate()
+
+}
+
+// distribute splits the data among the members of the group and returns the group as stored in the result
+func (s *Split) distribute(res *result.Result, p *Policy, data []byte) (*result.Group, error) {
+
+	var (
+		group   = &result.Group{Name: p.Name, Threshold: p.Threshold}
+		members = len(p.Groups) + len(p.Holders)
+		shares  [][]byte
+	)
+
+	if p.Threshold < 1 || p.Threshold > members {
+		return nil, fmt.Errorf(errInvalidGroup, p.Threshold, members, p.Name)
+	}
+
+	// a single member suffices, so every member receives the data itself
+	if p.Threshold == 1 {
+
+		for len(shares) < members {
+			shares = append(shares, data)
+		}
+
+	} else {
+
+		var err error
+
+		if shares, err = s.share(res, data, members, p.Threshold); err != nil {
+			return nil, err
+		}
+
+	}
+
+	for idx, sub := range p.Groups {
+
+		g, err := s.distribute(res, sub, shares[idx])
+
+		if err != nil {
+			return nil, err
+		}
+
+		group.Groups = append(group.Groups, g)
+
+	}
+
+	for idx, holder := range p.Holders {
+
+		share := shares[len(p.Groups)+idx]
+
+		part, err := holder.Encrypt(res, len(res.Parts)+1, share)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, errFailedToEncrypt)
+		}
+
+		if err := s.backup(res, part, share); err != nil {
+			return nil, err
+		}
+
+		group.Serials = append(group.Serials, part.Serial)
+		res.Parts = append(res.Parts, part)
+
+	}
+
+	return group, nil
+
+}
+
+// satisfy attempts to recover the data of the group from the shares collected so far and reports the groups that are not satisfied yet
+func (s *Split) satisfy(res *result.Result, g *result.Group, shares map[uint32][]byte) ([]byte, bool) {
+
+	var available [][]byte
+
+	for _, sub := range g.Groups {
+
+		if data, ok := s.satisfy(res, sub, shares); ok {
+			available = append(available, data)
+		}
+
+	}
+
+	for _, serial := range g.Serials {
+
+		if share, ok := shares[serial]; ok {
+			available = append(available, share)
+		}
+
+	}
+
+	if len(available) < g.Threshold {
+		s.out(logGroupUnsatisfied, g.Name, len(available), g.Threshold)
+		return nil, false
+	}
+
+	if g.Threshold == 1 {
+		return available[0], true
+	}
+
+	data, err := combine(res, available)
+
+	if err != nil {
+		s.out(logGroupFailed, g.Name, err)
+		return nil, false
+	}
+
+	return data, true
+
+}
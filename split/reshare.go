@@ -0,0 +1,79 @@
+package split
+
+import (
+	"fmt"
+
+	"github.com/kreuzwerker/yess/result"
+	"github.com/kreuzwerker/yess/stream"
+)
+
+const logResharing = "secret recovered, resharing result %s"
+
+// Reshare recovers the secret of the result in memory and splits it again with the given split function, e.g. for a new set of holders or a new threshold - the new result keeps the mode of the result and is linked to it by its ID. Streamed payloads are re-encrypted from the stream input into the stream output.
+func (s *Split) Reshare(res *result.Result, split func(secret []byte) (*result.Result, error)) (*result.Result, error) {
+
+	if res.Mode == result.ModeDEK && len(res.Payload) == 0 {
+		return nil, fmt.Errorf(errMissingPayload)
+	}
+
+	if res.Mode == result.ModeStream && s.Stream == nil {
+		return nil, fmt.Errorf(errMissingStream)
+	}
+
+	combined, _, _, err := s.collect(res)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.out(logResharing, res.ID)
+
+	var secret []byte
+
+	switch res.Mode {
+	case result.ModeStream:
+
+		r, err := stream.NewReader(s.Stream.In, streamKey(res, combined))
+
+		if err != nil {
+			return nil, err
+		}
+
+		s.Stream = &Stream{In: r, Out: s.Stream.Out}
+
+	case result.ModeDEK:
+
+		if secret, err = s.finish(res, combined); err != nil {
+			return nil, err
+		}
+
+		s.Hybrid = true
+
+	default:
+		secret = combined
+	}
+
+	// the secret only lives in memory until it has been split again
+	defer func() {
+
+		for _, b := range [][]byte{combined, secret} {
+
+			for i := range b {
+				b[i] = 0
+			}
+
+		}
+
+	}()
+
+	next, err := split(secret)
+
+	if err != nil {
+		return nil, err
+	}
+
+	next.Previous = res.ID
+
+	return next, nil
+
+}
@@ -0,0 +1,144 @@
+package split
+
+import (
+	"fmt"
+
+	"github.com/kreuzwerker/yess/device"
+	"github.com/kreuzwerker/yess/result"
+	"github.com/pkg/errors"
+)
+
+const (
+	errMissingPart     = "result has no part for device %d"
+	errMissingRewrapID = "result has no ID - please migrate it before rewrapping parts"
+	logRewrapped       = "moved part of device %d to device %d"
+)
+
+// Rewrap decrypts the part of the device with the given serial and encrypts the same shares to the recipient, which replaces the device as holder or backup device - no other device is required and the other parts stay untouched
+func (s *Split) Rewrap(res *result.Result, serial uint32, r device.Recipient) error {
+
+	if err := res.Validate(); err != nil {
+		return errors.Wrapf(err, errInvalidResult)
+	}
+
+	if res.ID == "" {
+		return fmt.Errorf(errMissingRewrapID)
+	}
+
+	part, stanza := res.Find(serial)
+
+	if part == nil {
+		return fmt.Errorf(errMissingPart, serial)
+	}
+
+	if other, _ := res.Find(r.Serial()); other != nil {
+		return fmt.Errorf(errDuplicateDeviceUsed, r.Serial())
+	}
+
+	d, err := s.next(func(candidate uint32) bool {
+		return candidate == serial
+	})
+
+	if err != nil {
+		return err
+	}
+
+	defer d.Close()
+
+	if d.Serial() != serial {
+		return errors.New(errInvalidDevice)
+	}
+
+	plaintext, err := d.Decrypt(res, stanza)
+
+	if err != nil {
+		return errors.Wrapf(err, errFailedToDecrypt, serial)
+	}
+
+	// a share that does not match the commitments or the x-coordinates of the part is not passed on
+	if _, err := check(res, part, plaintext); err != nil {
+		return err
+	}
+
+	// parts of version 1 results have no index, so the part takes the next one - the index of their secretbox stanzas is not authenticated
+	if part.Index == 0 {
+
+		index := 0
+
+		for _, p := range res.Parts {
+
+			if p.Index > index {
+				index = p.Index
+			}
+
+		}
+
+		part.Index = index + 1
+
+		for _, backup := range part.Backups {
+			backup.Index = part.Index
+		}
+
+	}
+
+	next, err := r.Encrypt(res, part.Index, plaintext)
+
+	if err != nil {
+		return errors.Wrapf(err, errFailedToEncrypt)
+	}
+
+	next.Weight = stanza.Weight
+	next.X = stanza.X
+
+	if stanza == part {
+
+		next.Backups = part.Backups
+
+		for idx := range res.Parts {
+
+			if res.Parts[idx] == part {
+				res.Parts[idx] = next
+			}
+
+		}
+
+		rename(res.Policy, serial, next.Serial)
+
+	} else {
+
+		for idx := range part.Backups {
+
+			if part.Backups[idx] == stanza {
+				part.Backups[idx] = next
+			}
+
+		}
+
+	}
+
+	s.out(logRewrapped, serial, next.Serial)
+
+	return nil
+
+}
+
+// rename replaces the serial of a holder in the groups of a policy
+func rename(g *result.Group, serial, next uint32) {
+
+	if g == nil {
+		return
+	}
+
+	for idx := range g.Serials {
+
+		if g.Serials[idx] == serial {
+			g.Serials[idx] = next
+		}
+
+	}
+
+	for _, group := range g.Groups {
+		rename(group, serial, next)
+	}
+
+}
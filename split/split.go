@@ -2,42 +2,104 @@ package split
 
 import (
 	"fmt"
-	"os"
 
+	"github.com/kreuzwerker/yess/device"
 	"github.com/kreuzwerker/yess/result"
-	shamir "github.com/kreuzwerker/yess/share"
-	"github.com/kreuzwerker/yess/yubikey"
 	"github.com/pkg/errors"
 )
 
 const (
-	errDuplicateDeviceUsed      = "duplicate device used (serial number %d)"
-	errFailedToConnectToYubikey = "failed to connect to Yubikey"
-	errFailedToEncrypt          = "failed to encrypt share"
-	errInvalidDevice            = "invalid device added - it was not part of the original share group"
-	logCandidateFound           = "candidate %d: serial %d, issuer %s, subject %s, expiry %s"
-	logConnectAndEnterPIN       = "please connect one of these devices and enter PIN (or press enter to use the default PIN)"
-	logPassedThresholdIssue     = "passed threshold, but share cannot be recovered yet (%s)"
-	logSplitting                = "splitting secret into %d yubikeys"
+	errAborted                 = "aborted PIN entry (%s)"
+	errDuplicateDeviceUsed     = "duplicate device used (serial number %d)"
+	errFailedToConnectToDevice = "failed to connect to device"
+	errFailedToDecrypt         = "failed to decrypt part of device %d"
+	errFailedToEncrypt         = "failed to encrypt share"
+	errFailedToListDevices     = "failed to list connected devices"
+	errInvalidDevice           = "invalid device added - it was not part of the original share group"
+	errInvalidGroup            = "invalid threshold %d for %d members of group %q"
+	errInvalidResult           = "invalid result"
+	errMissingPayload          = "result has no payload - please pass the payload file"
+	errMissingStream           = "result has a streamed payload - please pass the encrypted payload"
+	errNotRecoverable          = "secret cannot be recovered from %d shares, %d devices failed"
+	errReservedParts           = "device %d has no weight, but the remaining parts are reserved for the weighted devices"
+	errTooManyWeights          = "%d weighted devices exceed %d parts"
+	logBackupFound             = "candidate %d: backup serial %d, issuer %s, subject %s, expiry %s"
+	logCandidateFound          = "candidate %d: serial %d, issuer %s, subject %s, expiry %s"
+	logConnectAndEnterPIN      = "please connect one of these devices and enter PIN (or press enter to use the default PIN)"
+	logEnterPINForDevice       = "please enter PIN of device %d in reader %q (or press enter to use the default PIN)"
+	logPassedThresholdIssue    = "passed threshold, but share cannot be recovered yet (%s)"
+	logSkipped                 = "skipping device: %s"
+	logSplitting               = "splitting secret into %d devices"
+	logSplittingTo             = "splitting secret into %d recipients"
 )
 
+// aborted wraps errors of the PIN entry, which abort a combination
+type aborted struct {
+	error
+}
+
+// Error returns the error message of the wrapped error
+func (a aborted) Error() string {
+	return fmt.Sprintf(errAborted, a.error)
+}
+
 type Split struct {
-	out func(string, ...interface{})
+	Hybrid   bool                          // Hybrid encrypts the secret with a random data encryption key (DEK) and splits the DEK instead
+	Prime    bool                          // Prime enables Shamir secret sharing over a 256 bit prime field, which allows more than 255 parts
+	Backups  map[uint32][]device.Recipient // Backups encrypts the parts of the devices with the given serials to the backup devices of their holders as well
+	Weights  map[uint32]int                // Weights assigns the devices with the given serials more than one share
+	Stream   *Stream                       // Stream enables the streaming mode, which also splits a DEK and ignores the secret passed to the split functions
+	UnknownX bool                          // UnknownX allows adding holders while the x-coordinates of parts are unknown, although a new share may coincide with one of them
+	VSS      bool                          // VSS enables Feldman verifiable secret sharing of a DEK, which allows holders to verify their share
+	backend  device.Backend
+	out      func(string, ...interface{})
+	pin      func() (string, error)
 }
 
-func New(out func(string, ...interface{})) *Split {
+// New returns a split service that connects to devices through the given backend, reports progress through out and reads PINs through pin
+func New(backend device.Backend, out func(string, ...interface{}), pin func() (string, error)) *Split {
 
 	return &Split{
-		out: out,
+		backend: backend,
+		out:     out,
+		pin:     pin,
 	}
 
 }
 
+// Combine collects shares from the devices of the given result until the secret can be recovered - devices that are invalid, already used or fail are reported and skipped, wrong PINs can be retried and aborting PIN entry aborts the combination. Once more than threshold shares have been collected, subsets are tried to recover the secret despite inconsistent shares, which are reported. In streaming mode, the secret is written to the stream output instead of being returned.
 func (s *Split) Combine(res *result.Result) ([]byte, error) {
 
+	if res.Mode == result.ModeDEK && len(res.Payload) == 0 {
+		return nil, fmt.Errorf(errMissingPayload)
+	}
+
+	if res.Mode == result.ModeStream && s.Stream == nil {
+		return nil, fmt.Errorf(errMissingStream)
+	}
+
+	combined, _, _, err := s.collect(res)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.finish(res, combined)
+
+}
+
+// collect validates the result and collects shares from its devices until the shared data can be recovered - it returns the data along with the consistent shares and their parts
+func (s *Split) collect(res *result.Result) ([]byte, [][]byte, []*result.Part, error) {
+
+	if err := res.Validate(); err != nil {
+		return nil, nil, nil, errors.Wrapf(err, errInvalidResult)
+	}
+
 	var (
-		mapping = make(map[uint32]*result.Part)
-		shares  [][]byte
+		failed = make(map[uint32]bool) // failed devices by serial
+		parts  []*result.Part
+		shares [][]byte
+		used   = make(map[uint32]bool) // credited parts by serial of the holder
 	)
 
 	for idx, part := range res.Parts {
@@ -50,63 +112,205 @@ func (s *Split) Combine(res *result.Result) ([]byte, error) {
 			part.Expiry,
 		)
 
-		mapping[part.Serial] = part
+		for _, backup := range part.Backups {
+
+			s.out(logBackupFound,
+				idx+1,
+				backup.Serial,
+				backup.Issuer,
+				backup.Subject,
+				backup.Expiry,
+			)
+
+		}
 
 	}
 
-	for {
+	// parts remain as long as one of their devices has not failed
+	remaining := func() bool {
 
-		pin, err := s.pin()
+		for _, part := range res.Parts {
+
+			for _, stanza := range part.Stanzas() {
+
+				if !used[part.Serial] && !failed[stanza.Serial] {
+					return true
+				}
+
+			}
 
-		if err != nil {
-			return nil, err
 		}
 
-		y, err := yubikey.New(pin)
+		return false
+
+	}
+
+	for remaining() {
+
+		d, err := s.next(func(serial uint32) bool {
+			part, _ := res.Find(serial)
+			return part != nil && !used[part.Serial] && !failed[serial]
+		})
 
 		if err != nil {
-			return nil, errors.Wrapf(err, errFailedToConnectToYubikey)
+
+			var perr *device.PINError
+
+			if _, ok := err.(aborted); ok {
+				return nil, nil, nil, err
+			} else if errors.As(err, &perr) && perr.Locked() {
+
+				if part, _ := res.Find(perr.Serial); part != nil {
+					failed[perr.Serial] = true
+				}
+
+			}
+
+			s.out(logSkipped, err)
+
+			continue
+
 		}
 
-		part, ok := mapping[y.Serial]
+		serial := d.Serial()
+		part, stanza := res.Find(serial)
+
+		if part == nil {
+			d.Close()
+			s.out(logSkipped, errors.New(errInvalidDevice))
+			continue
+		}
 
-		if !ok {
-			return nil, errors.New(errInvalidDevice)
+		if used[part.Serial] || failed[serial] {
+			d.Close()
+			s.out(logSkipped, fmt.Errorf(errDuplicateDeviceUsed, serial))
+			continue
 		}
 
-		share, err := y.Decrypt(part)
+		plaintext, err := d.Decrypt(res, stanza)
+
+		d.Close()
 
 		if err != nil {
-			return nil, err
+			failed[serial] = true
+			s.out(logSkipped, errors.Wrapf(err, errFailedToDecrypt, serial))
+			continue
+		}
+
+		// verifiable shares and recorded x-coordinates are checked right away
+		credited, err := check(res, part, plaintext)
+
+		if err != nil {
+			failed[serial] = true
+			s.out(logSkipped, err)
+			continue
+		}
+
+		used[part.Serial] = true
+
+		for _, share := range credited {
+			parts = append(parts, part)
+			shares = append(shares, share)
+		}
+
+		if res.Policy != nil {
+
+			collected := make(map[uint32][]byte)
+
+			for idx, part := range parts {
+				collected[part.Serial] = shares[idx]
+			}
+
+			if combined, ok := s.satisfy(res, res.Policy, collected); ok {
+				return combined, shares, parts, nil
+			}
+
+			continue
+
+		}
+
+		if len(shares) < res.Threshold {
+			continue
 		}
 
-		shares = append(shares, share)
+		combined, cheaters, err := reconstruct(res, shares, parts, len(credited))
+
+		if err != nil {
+			s.out(logPassedThresholdIssue, err)
+			continue
+		}
 
-		if len(shares) >= res.Threshold {
+		inconsistent := make(map[*result.Part]bool)
 
-			secret, err := shamir.Combine(shares)
+		// the shares of weighted parts are reported once
+		for _, cheater := range cheaters {
 
-			if err != nil {
-				s.out(logPassedThresholdIssue, err)
+			if !inconsistent[cheater] {
+				s.out(logInconsistentPart, cheater.Serial, cheater.Subject)
 			}
 
-			return secret, nil
+			inconsistent[cheater] = true
 
 		}
 
+		var (
+			consistent      [][]byte
+			consistentParts []*result.Part
+		)
+
+		for idx, part := range parts {
+
+			if !inconsistent[part] {
+				consistent = append(consistent, shares[idx])
+				consistentParts = append(consistentParts, part)
+			}
+
+		}
+
+		return combined, consistent, consistentParts, nil
+
 	}
 
+	return nil, nil, nil, fmt.Errorf(errNotRecoverable, len(shares), len(failed))
+
 }
 
 func (s *Split) Split(secret []byte, parts, threshold int) (*result.Result, error) {
 
+	if len(s.Weights) > parts {
+		return nil, fmt.Errorf(errTooManyWeights, len(s.Weights), parts)
+	}
+
 	mapping := make(map[uint32]interface{})
 
-	result := &result.Result{
-		Threshold: threshold,
+	// backup devices cannot hold parts of their own
+	for _, backups := range s.Backups {
+
+		for _, backup := range backups {
+			mapping[backup.Serial()] = struct{}{}
+		}
+
+	}
+
+	result, err := result.New(threshold)
+
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := s.prepare(result, secret)
+
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := s.count(parts)
+
+	if err != nil {
+		return nil, err
 	}
 
-	shares, err := shamir.Split(secret, parts, threshold)
+	shares, err := s.share(result, shared, count, threshold)
 
 	if err != nil {
 		return nil, err
@@ -114,35 +318,133 @@ func (s *Split) Split(secret []byte, parts, threshold int) (*result.Result, erro
 
 	s.out(logSplitting, parts)
 
-	for _, share := range shares {
+	// the last parts are reserved for weighted devices that have not been inserted yet, since their shares have been generated already
+	reserved := func(serial uint32) bool {
 
-		pin, err := s.pin()
+		var missing int
+
+		for weighted := range s.Weights {
+
+			if _, ok := mapping[weighted]; !ok {
+				missing++
+			}
+
+		}
+
+		_, weighted := s.Weights[serial]
+
+		return !weighted && parts-len(result.Parts) <= missing
+
+	}
+
+	for len(result.Parts) < parts {
+
+		d, err := s.next(func(serial uint32) bool {
+			_, ok := mapping[serial]
+			return !ok && !reserved(serial)
+		})
 
 		if err != nil {
 			return nil, err
 		}
 
-		y, err := yubikey.New(pin)
+		if _, ok := mapping[d.Serial()]; ok {
+			d.Close()
+			return nil, fmt.Errorf(errDuplicateDeviceUsed, d.Serial())
+		}
 
-		if err != nil {
-			return nil, errors.Wrapf(err, errFailedToConnectToYubikey)
+		if reserved(d.Serial()) {
+			d.Close()
+			s.out(logSkipped, fmt.Errorf(errReservedParts, d.Serial()))
+			continue
 		}
 
-		part, err := y.Encrypt(share)
+		part, err := s.assign(result, d, shares)
+
+		d.Close()
 
 		if err != nil {
-			return nil, errors.Wrapf(err, errFailedToConnectToYubikey)
+			return nil, errors.Wrapf(err, errFailedToEncrypt)
 		}
 
-		if _, ok := mapping[part.Serial]; ok {
-			return nil, fmt.Errorf(errDuplicateDeviceUsed, part.Serial)
+		for _, stanza := range part.Stanzas() {
+			mapping[stanza.Serial] = struct{}{}
 		}
 
-		mapping[part.Serial] = struct{}{}
+		shares = shares[s.weight(part.Serial):]
 
 		result.Parts = append(result.Parts, part)
 
-		y.Close()
+	}
+
+	return result, result.Validate()
+
+}
+
+// SplitTo splits the secret into one part per recipient without connecting to any device, since encryption only requires the public keys
+func (s *Split) SplitTo(secret []byte, recipients []device.Recipient, threshold int) (*result.Result, error) {
+
+	mapping := make(map[uint32]interface{})
+
+	for _, recipient := range recipients {
+
+		for _, serial := range s.serials(recipient) {
+
+			if _, ok := mapping[serial]; ok {
+				return nil, fmt.Errorf(errDuplicateDeviceUsed, serial)
+			}
+
+			mapping[serial] = struct{}{}
+
+		}
+
+	}
+
+	result, err := result.New(threshold)
+
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := s.prepare(result, secret)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var count int
+
+	for _, recipient := range recipients {
+
+		weight := s.weight(recipient.Serial())
+
+		if weight < 1 {
+			return nil, fmt.Errorf(errInvalidWeight, weight, recipient.Serial())
+		}
+
+		count += weight
+
+	}
+
+	shares, err := s.share(result, shared, count, threshold)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.out(logSplittingTo, len(recipients))
+
+	for _, recipient := range recipients {
+
+		part, err := s.assign(result, recipient, shares)
+
+		if err != nil {
+			return nil, errors.Wrapf(err, errFailedToEncrypt)
+		}
+
+		shares = shares[s.weight(recipient.Serial()):]
+
+		result.Parts = append(result.Parts, part)
 
 	}
 
@@ -150,16 +452,69 @@ func (s *Split) Split(secret []byte, parts, threshold int) (*result.Result, erro
 
 }
 
-func (s *Split) pin() (string, error) {
+// next connects to the next device - an already connected device accepted by want is preferred, otherwise the user is asked to connect one
+func (s *Split) next(want func(uint32) bool) (device.Device, error) {
+
+	info, err := s.find(want)
+
+	if err != nil {
+		return nil, err
+	}
 
-	pin, err := yubikey.PIN(func() {
+	if info != nil {
+		s.out(logEnterPINForDevice, info.Serial, info.Reader)
+	} else {
 		s.out(logConnectAndEnterPIN)
-	}, os.Stderr)
+	}
+
+	pin, err := s.pin()
 
 	if err != nil {
-		return "", err
+		return nil, aborted{err}
+	}
+
+	// the device may have been connected while the PIN was entered
+	if info == nil {
+
+		if info, err = s.find(want); err != nil {
+			return nil, err
+		}
+
+	}
+
+	var d device.Device
+
+	if info != nil {
+		d, err = s.backend.Connect(info, pin)
+	} else {
+		d, err = s.backend.Open(pin)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToConnectToDevice)
+	}
+
+	return d, nil
+
+}
+
+// find returns the first connected device accepted by want, if any
+func (s *Split) find(want func(uint32) bool) (*device.Info, error) {
+
+	infos, err := s.backend.Devices()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToListDevices)
+	}
+
+	for _, info := range infos {
+
+		if info.Err == nil && want(info.Serial) {
+			return info, nil
+		}
+
 	}
 
-	return pin, nil
+	return nil, nil
 
 }
@@ -0,0 +1,1401 @@
+package split
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/kreuzwerker/yess/device"
+	"github.com/kreuzwerker/yess/encrypt"
+	"github.com/kreuzwerker/yess/recipient"
+	"github.com/kreuzwerker/yess/result"
+	shamir "github.com/kreuzwerker/yess/share"
+	"github.com/kreuzwerker/yess/soft"
+	"github.com/stretchr/testify/assert"
+)
+
+func devices(t *testing.T, curves ...elliptic.Curve) []*soft.Soft {
+
+	var devices []*soft.Soft
+
+	for idx, curve := range curves {
+
+		d, err := soft.Generate(curve, uint32(idx+1), soft.DefaultPIN)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		devices = append(devices, d)
+
+	}
+
+	return devices
+
+}
+
+// pins returns a PIN entry function that returns the given PINs, followed by the default PIN up to a total of 10 entries before aborting
+// legacy returns a result of version 1 for the given devices, which has no ID and uses the given random x-coordinates
+func legacy(t *testing.T, devices []*soft.Soft, xs ...uint8) *result.Result {
+
+	assert := assert.New(t)
+
+	shps, err := shamir.Split([]byte("my secret"), 2, 2)
+
+	assert.NoError(err)
+
+	res := &result.Result{Threshold: 2, Version: result.Version}
+
+	for idx, x := range xs {
+
+		shp, err := shamir.Extend(shps, x)
+
+		assert.NoError(err)
+
+		eks, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+		assert.NoError(err)
+
+		dkp := devices[idx].Certificate().PublicKey.(*ecdsa.PublicKey)
+		sk, _ := elliptic.P256().ScalarMult(dkp.X, dkp.Y, eks.D.Bytes())
+
+		part := &result.Part{
+			Algorithm: result.Algorithm{
+				Cipher:       result.CipherSecretbox,
+				KDF:          result.KDFSHA3,
+				KeyAgreement: result.KeyAgreementECDH,
+			},
+			Serial: devices[idx].Serial(),
+			Share:  encrypt.Encrypt(sk.Bytes(), shp),
+		}
+
+		assert.NoError(part.AddKey(&eks.PublicKey))
+
+		res.Parts = append(res.Parts, part)
+
+	}
+
+	return res
+
+}
+
+func pins(values ...string) func() (string, error) {
+
+	var count int
+
+	return func() (string, error) {
+
+		defer func() {
+			count++
+		}()
+
+		if count < len(values) {
+			return values[count], nil
+		} else if count < 10 {
+			return soft.DefaultPIN, nil
+		}
+
+		return "", io.EOF
+
+	}
+
+}
+
+// record returns a message function that logs and records the messages
+func record(t *testing.T, msgs *[]string) func(string, ...interface{}) {
+
+	return func(msg string, args ...interface{}) {
+		t.Logf(msg, args...)
+		*msgs = append(*msgs, fmt.Sprintf(msg, args...))
+	}
+
+}
+
+func service(t *testing.T, devices ...*soft.Soft) *Split {
+	return New(soft.NewBackend(devices...), t.Logf, pins())
+}
+
+func TestSplitAndCombine(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P384(), elliptic.P256())
+
+	res, err := service(t, devices...).Split([]byte("my secret"), 3, 2)
+
+	assert.NoError(err)
+	assert.Equal(2, res.Threshold)
+	assert.Len(res.Parts, 3)
+
+	var buf bytes.Buffer
+
+	assert.NoError(res.Save(&buf))
+
+	res, err = result.Load(&buf)
+
+	assert.NoError(err)
+
+	// combine with the second and third device
+	secret, err := service(t, devices[1], devices[2]).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+
+}
+
+func TestSplitAndCombineRSA(t *testing.T) {
+
+	assert := assert.New(t)
+
+	rsa, err := soft.GenerateRSA(2048, 4, soft.DefaultPIN)
+
+	assert.NoError(err)
+
+	devices := append(devices(t, elliptic.P384()), rsa)
+
+	res, err := service(t, devices...).Split([]byte("my secret"), 2, 2)
+
+	assert.NoError(err)
+	assert.Equal(result.KeyAgreementECDH, res.Parts[0].Algorithm.KeyAgreement)
+	assert.Equal(result.KeyAgreementRSA, res.Parts[1].Algorithm.KeyAgreement)
+
+	secret, err := service(t, devices[1], devices[0]).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+
+}
+
+func TestSplitAndCombineX25519(t *testing.T) {
+
+	assert := assert.New(t)
+
+	x25519, err := soft.GenerateX25519(4, soft.DefaultPIN)
+
+	assert.NoError(err)
+
+	devices := append(devices(t, elliptic.P256()), x25519)
+
+	res, err := service(t, devices...).Split([]byte("my secret"), 2, 2)
+
+	assert.NoError(err)
+
+	secret, err := service(t, devices[1], devices[0]).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+
+}
+
+func TestSplitAndCombineRetiredSlot(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256())
+
+	assert.NoError(devices[1].SetSlot("82"))
+
+	res, err := service(t, devices...).Split([]byte("my secret"), 2, 2)
+
+	assert.NoError(err)
+	assert.Equal("9d", res.Parts[0].Slot)
+	assert.Equal("82", res.Parts[1].Slot)
+
+	secret, err := service(t, devices...).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+
+	// the key has been rotated to another slot since
+	assert.NoError(devices[1].SetSlot("83"))
+
+	var msgs []string
+
+	_, err = New(soft.NewBackend(devices[1]), record(t, &msgs), pins()).Combine(res)
+
+	assert.EqualError(err, "aborted PIN entry (EOF)")
+	assert.Contains(msgs, "skipping device: failed to decrypt part of device 2: no certificate present in slot 82 of device 2")
+
+}
+
+func TestSplitToAndCombine(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P384(), elliptic.P256())
+
+	var recipients []device.Recipient
+
+	for _, d := range devices {
+
+		// the certificate serial number is unrelated to the device serial
+		assert.NotEqual(uint64(d.Serial()), d.Certificate().SerialNumber.Uint64())
+
+		r, err := recipient.New(d.Certificate(), d.Slot())
+
+		assert.NoError(err)
+		assert.Equal(d.Serial(), r.Serial())
+
+		recipients = append(recipients, r)
+
+	}
+
+	res, err := service(t).SplitTo([]byte("my secret"), recipients, 2)
+
+	assert.NoError(err)
+	assert.Len(res.Parts, 3)
+
+	secret, err := service(t, devices[2], devices[0]).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+
+	_, err = service(t).SplitTo([]byte("my secret"), append(recipients, recipients[0]), 2)
+
+	assert.EqualError(err, "duplicate device used (serial number 1)")
+
+}
+
+func TestSplitDuplicateDevice(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256())
+
+	res, err := service(t, devices...).Split([]byte("my secret"), 3, 2)
+
+	assert.EqualError(err, "duplicate device used (serial number 1)")
+	assert.Nil(res)
+
+}
+
+func TestCombineInvalidDevice(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256(), elliptic.P256())
+
+	res, err := service(t, devices[0], devices[1]).Split([]byte("my secret"), 2, 2)
+
+	assert.NoError(err)
+
+	var msgs []string
+
+	secret, err := New(soft.NewBackend(devices[2]), record(t, &msgs), pins()).Combine(res)
+
+	assert.EqualError(err, "aborted PIN entry (EOF)")
+	assert.Nil(secret)
+	assert.Contains(msgs, "skipping device: invalid device added - it was not part of the original share group")
+
+}
+
+func TestCombineDuplicateDevice(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256(), elliptic.P256())
+
+	res, err := service(t, devices...).Split([]byte("my secret"), 3, 2)
+
+	assert.NoError(err)
+
+	var msgs []string
+
+	secret, err := New(soft.NewBackend(devices[0], devices[0], devices[1]), record(t, &msgs), pins()).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+	assert.Contains(msgs, "skipping device: duplicate device used (serial number 1)")
+
+}
+
+func TestCombineWrongPIN(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256())
+
+	res, err := service(t, devices...).Split([]byte("my secret"), 2, 2)
+
+	assert.NoError(err)
+
+	var msgs []string
+
+	backend := soft.NewBackend(devices...)
+	backend.Hub = true
+
+	secret, err := New(backend, record(t, &msgs), pins("000000", "000000")).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+	assert.Contains(msgs, "skipping device: failed to connect to device: wrong PIN for device 1 (2 retries remaining)")
+	assert.Contains(msgs, "skipping device: failed to connect to device: wrong PIN for device 1 (1 retries remaining)")
+	assert.Equal(soft.Retries, devices[0].Retries())
+
+	// a locked device is skipped for good
+	for devices[1].Retries() > 0 {
+		devices[1].Login("000000")
+	}
+
+	msgs = nil
+
+	_, err = New(backend, record(t, &msgs), pins()).Combine(res)
+
+	assert.EqualError(err, "secret cannot be recovered from 1 shares, 1 devices failed")
+	assert.Contains(msgs, "skipping device: failed to connect to device: PIN of device 2 is locked")
+
+}
+
+func TestCombineFailedDecryption(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256(), elliptic.P256())
+
+	res, err := service(t, devices...).Split([]byte("my secret"), 3, 2)
+
+	assert.NoError(err)
+
+	res.Parts[0].Share[0] ^= 0xff
+
+	var msgs []string
+
+	secret, err := New(soft.NewBackend(devices...), record(t, &msgs), pins()).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+	assert.Contains(msgs, "skipping device: failed to decrypt part of device 1: failed to authenticate share - wrong key or tampered result")
+
+}
+
+func TestSplitAndCombineHub(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P384(), elliptic.P256(), elliptic.P256())
+
+	var (
+		backend = soft.NewBackend(devices...)
+		prompts []string
+	)
+
+	backend.Hub = true
+
+	s := New(backend, func(msg string, args ...interface{}) {
+		prompts = append(prompts, fmt.Sprintf(msg, args...))
+	}, func() (string, error) {
+		return soft.DefaultPIN, nil
+	})
+
+	res, err := s.Split([]byte("my secret"), 3, 2)
+
+	assert.NoError(err)
+	assert.Len(res.Parts, 3)
+
+	for idx, part := range res.Parts {
+		assert.Equal(uint32(idx+1), part.Serial)
+	}
+
+	prompts = nil
+
+	// all devices stay connected - the first part is lost, so the first device must be skipped
+	res.Parts = res.Parts[1:]
+
+	secret, err := s.Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+	assert.Contains(prompts, `please enter PIN of device 2 in reader "Soft Device 2" (or press enter to use the default PIN)`)
+	assert.Contains(prompts, `please enter PIN of device 3 in reader "Soft Device 3" (or press enter to use the default PIN)`)
+
+}
+
+func TestCombineTampered(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256(), elliptic.P256())
+
+	res, err := service(t, devices...).Split([]byte("my secret"), 3, 2)
+
+	assert.NoError(err)
+
+	res.Threshold = 4
+
+	_, err = service(t, devices...).Combine(res)
+
+	assert.EqualError(err, "invalid result: invalid threshold 4 for 3 shares")
+
+	// shares are bound to their parts and the threshold
+	res.Threshold = 3
+	res.Parts[0].Share, res.Parts[1].Share = res.Parts[1].Share, res.Parts[0].Share
+	res.Parts[2].Subject = "CN=mallory"
+
+	var msgs []string
+
+	_, err = New(soft.NewBackend(devices...), record(t, &msgs), pins()).Combine(res)
+
+	assert.EqualError(err, "secret cannot be recovered from 0 shares, 3 devices failed")
+
+	for serial := 1; serial <= 3; serial++ {
+		assert.Contains(msgs, fmt.Sprintf("skipping device: failed to decrypt part of device %d: failed to authenticate share - wrong key or tampered result", serial))
+	}
+
+	// shares are bound to the mode, the sharing scheme and the commitments as well
+	for vss, tamper := range map[bool]func(res *result.Result){
+		false: func(res *result.Result) {
+			res.Mode = result.ModeSecret
+			res.Sharing = result.SharingShamirP256
+		},
+		true: func(res *result.Result) {
+			res.Commitments[1], res.Commitments[2] = res.Commitments[2], res.Commitments[1]
+		},
+	} {
+
+		s := service(t, devices...)
+		s.Hybrid = true
+		s.VSS = vss
+
+		res, err := s.Split([]byte("my secret"), 3, 3)
+
+		assert.NoError(err)
+
+		tamper(res)
+
+		msgs = nil
+
+		_, err = New(soft.NewBackend(devices...), record(t, &msgs), pins()).Combine(res)
+
+		assert.EqualError(err, "secret cannot be recovered from 0 shares, 3 devices failed")
+		assert.Contains(msgs, "skipping device: failed to decrypt part of device 1: failed to authenticate share - wrong key or tampered result")
+
+	}
+
+}
+
+func TestSplitAndCombineHybrid(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P384(), elliptic.P256())
+
+	secret := bytes.Repeat([]byte("my secret"), 10000)
+
+	s := service(t, devices...)
+	s.Hybrid = true
+
+	res, err := s.Split(secret, 3, 2)
+
+	assert.NoError(err)
+	assert.Equal(result.ModeDEK, res.Mode)
+	assert.True(len(res.Payload) > len(secret))
+
+	// only the DEK and its hash are split
+	for _, part := range res.Parts {
+		assert.True(len(part.Share) < 100)
+	}
+
+	combined, err := service(t, devices[2], devices[1]).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal(secret, combined)
+
+	res.Payload[0] ^= 0xff
+
+	_, err = service(t, devices[2], devices[1]).Combine(res)
+
+	assert.EqualError(err, "failed to decrypt payload - it has been tampered with or belongs to another result")
+
+	res.Payload = nil
+
+	_, err = service(t, devices[2], devices[1]).Combine(res)
+
+	assert.EqualError(err, "result has no payload - please pass the payload file")
+
+}
+
+func TestSplitAndCombineStream(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256())
+
+	secret := bytes.Repeat([]byte("my secret"), 20000)
+
+	var payload bytes.Buffer
+
+	s := service(t, devices...)
+	s.Stream = &Stream{In: bytes.NewReader(secret), Out: &payload}
+
+	res, err := s.Split(nil, 2, 2)
+
+	assert.NoError(err)
+	assert.Equal(result.ModeStream, res.Mode)
+	assert.Empty(res.Payload)
+
+	_, err = service(t, devices...).Combine(res)
+
+	assert.EqualError(err, "result has a streamed payload - please pass the encrypted payload")
+
+	var out bytes.Buffer
+
+	s = service(t, devices...)
+	s.Stream = &Stream{In: bytes.NewReader(payload.Bytes()), Out: &out}
+
+	combined, err := s.Combine(res)
+
+	assert.NoError(err)
+	assert.Nil(combined)
+	assert.Equal(secret, out.Bytes())
+
+	// truncation is detected
+	s = service(t, devices...)
+	s.Stream = &Stream{In: bytes.NewReader(payload.Bytes()[:payload.Len()-1]), Out: ioutil.Discard}
+
+	_, err = s.Combine(res)
+
+	assert.EqualError(err, "failed to stream payload: failed to decrypt chunk 2 - the payload has been tampered with or truncated")
+
+}
+
+func TestSplitAndVerifyShare(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P384(), elliptic.P256())
+
+	secret := []byte("my secret")
+
+	s := service(t, devices...)
+	s.VSS = true
+
+	res, err := s.Split(secret, 3, 2)
+
+	assert.NoError(err)
+	assert.Equal(result.ModeDEK, res.Mode)
+	assert.Equal(result.SharingFeldman, res.Sharing)
+	assert.Len(res.Commitments, 2)
+
+	for _, d := range devices {
+
+		serial, err := service(t, d).VerifyShare(res)
+
+		assert.NoError(err)
+		assert.Equal(d.Serial(), serial)
+
+	}
+
+	combined, err := service(t, devices[2], devices[0]).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal(secret, combined)
+
+	// a dealer encrypting an inconsistent share is detected by its holder
+	assert.NoError(devices[1].Login(soft.DefaultPIN))
+
+	share, err := devices[1].Decrypt(res, res.Parts[1])
+
+	assert.NoError(err)
+
+	share[len(share)-1] ^= 1
+
+	part, err := devices[1].Encrypt(res, 2, share)
+
+	assert.NoError(err)
+
+	res.Parts[1] = part
+
+	assert.NoError(devices[1].Close())
+
+	_, err = service(t, devices[1]).VerifyShare(res)
+
+	assert.EqualError(err, "share 2 does not match the commitments")
+
+	// and skipped when combining
+	var msgs []string
+
+	combined, err = New(soft.NewBackend(devices[1], devices[0], devices[2]), record(t, &msgs), pins()).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal(secret, combined)
+	assert.Contains(msgs, "skipping device: share 2 does not match the commitments")
+
+	// results without commitments cannot be verified
+	res, err = service(t, devices...).Split(secret, 3, 2)
+
+	assert.NoError(err)
+
+	_, err = service(t, devices[0]).VerifyShare(res)
+
+	assert.EqualError(err, "result has no commitments - only results split with verifiable sharing can be verified")
+
+}
+
+func TestCombineCheater(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256(), elliptic.P256(), elliptic.P256())
+
+	res, err := service(t, devices...).Split([]byte("my secret"), 4, 2)
+
+	assert.NoError(err)
+
+	// the holder of the second device received a corrupted share
+	assert.NoError(devices[1].Login(soft.DefaultPIN))
+
+	share, err := devices[1].Decrypt(res, res.Parts[1])
+
+	assert.NoError(err)
+
+	share[0] ^= 1
+
+	part, err := devices[1].Encrypt(res, 2, share)
+
+	assert.NoError(err)
+	assert.NoError(devices[1].Close())
+
+	res.Parts[1] = part
+
+	var msgs []string
+
+	secret, err := New(soft.NewBackend(devices[1], devices[3], devices[2]), record(t, &msgs), pins()).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+	assert.Contains(msgs, "passed threshold, but share cannot be recovered yet (invalid hash, parts are missing)")
+	assert.Contains(msgs, "part of device 2 (subject CN=yess soft device 2) is inconsistent with the recovered secret - it has been corrupted or issued maliciously")
+
+	// with exactly threshold shares, the corrupted part cannot be identified
+	_, err = New(soft.NewBackend(devices[1], devices[3]), record(t, &msgs), pins()).Combine(res)
+
+	assert.Error(err)
+
+}
+
+func TestCombineCheaterWeighted(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256(), elliptic.P256())
+
+	s := service(t)
+	s.Weights = map[uint32]int{devices[2].Serial(): 2}
+
+	res, err := s.SplitTo([]byte("my secret"), []device.Recipient{devices[0], devices[1], devices[2]}, 3)
+
+	assert.NoError(err)
+
+	// the second share of the weighted part is corrupted
+	assert.NoError(devices[2].Login(soft.DefaultPIN))
+
+	plaintext, err := devices[2].Decrypt(res, res.Parts[2])
+
+	assert.NoError(err)
+
+	plaintext[len(plaintext)/2] ^= 1
+
+	part, err := devices[2].Encrypt(res, 3, plaintext)
+
+	assert.NoError(err)
+	assert.NoError(devices[2].Close())
+
+	part.Weight = res.Parts[2].Weight
+	part.X = res.Parts[2].X
+	res.Parts[2] = part
+
+	// the weighted part credits two shares at once, so the consistent subset contains only the first of them
+	var msgs []string
+
+	secret, err := New(soft.NewBackend(devices...), record(t, &msgs), pins()).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+	assert.Contains(msgs, "part of device 3 (subject CN=yess soft device 3) is inconsistent with the recovered secret - it has been corrupted or issued maliciously")
+
+}
+
+func TestSubsets(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var all [][]int
+
+	subsets(4, 2, func(indices []int) bool {
+		all = append(all, indices)
+		return false
+	})
+
+	assert.Equal([][]int{{0, 1}, {0, 2}, {0, 3}, {1, 2}, {1, 3}, {2, 3}}, all)
+
+	all = nil
+
+	subsets(3, 0, func(indices []int) bool {
+		all = append(all, indices)
+		return false
+	})
+
+	assert.Len(all, 1)
+	assert.Empty(all[0])
+
+	subsets(2, 3, func(indices []int) bool {
+		t.Fatal("unexpected subset")
+		return false
+	})
+
+}
+
+func TestAddHolders(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P384(), elliptic.P256(), elliptic.P256(), elliptic.P256())
+
+	for _, vss := range []bool{false, true} {
+
+		s := service(t, devices[:3]...)
+		s.VSS = vss
+
+		res, err := s.Split([]byte("my secret"), 3, 2)
+
+		assert.NoError(err)
+
+		for idx, part := range res.Parts {
+			assert.Equal(idx+1, part.X)
+		}
+
+		err = service(t, devices[0]).AddHolders(res, []device.Recipient{devices[1]})
+
+		assert.EqualError(err, "duplicate device used (serial number 2)")
+
+		err = service(t, devices[2], devices[0]).AddHolders(res, []device.Recipient{devices[3], devices[4]})
+
+		assert.NoError(err)
+		assert.Len(res.Parts, 5)
+		assert.Equal(4, res.Parts[3].X)
+		assert.Equal(5, res.Parts[4].Index)
+
+		// the new holders can combine the secret with each other and with the existing holders
+		secret, err := service(t, devices[4], devices[3]).Combine(res)
+
+		assert.NoError(err)
+		assert.Equal("my secret", string(secret))
+
+		secret, err = service(t, devices[1], devices[4]).Combine(res)
+
+		assert.NoError(err)
+		assert.Equal("my secret", string(secret))
+
+	}
+
+	// parts of older results have no recorded x-coordinate until they take part in a combination
+	res, err := service(t, devices[:3]...).Split([]byte("my secret"), 3, 2)
+
+	assert.NoError(err)
+
+	for _, part := range res.Parts {
+		part.X = 0
+	}
+
+	assert.NoError(service(t, devices[2], devices[0]).Migrate(res))
+	assert.Equal(0, res.Parts[1].X)
+
+	err = service(t, devices[1], devices[0]).AddHolders(res, []device.Recipient{devices[3]})
+
+	assert.NoError(err)
+	assert.Equal(2, res.Parts[1].X)
+	assert.Equal(4, res.Parts[3].X)
+
+	// a recorded x-coordinate must match the share
+	res.Parts[0].X = 5
+
+	var msgs []string
+
+	_, err = New(soft.NewBackend(devices[0], devices[1], devices[2]), record(t, &msgs), pins()).Combine(res)
+
+	assert.NoError(err)
+	assert.Contains(msgs, "skipping device: share of device 1 has x-coordinate 1 instead of 5")
+
+}
+
+func TestMigrateAndAddHolders(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256(), elliptic.P256(), elliptic.P256())
+
+	res := legacy(t, devices[:3], 200, 17, 93)
+
+	err := service(t, devices[0], devices[2]).AddHolders(res, []device.Recipient{devices[3]})
+
+	assert.EqualError(err, "result has no ID - please migrate it before adding holders")
+
+	err = service(t, devices[2], devices[0]).Migrate(res)
+
+	assert.NoError(err)
+	assert.NotEmpty(res.ID)
+	assert.Equal(200, res.Parts[0].X)
+	assert.Equal(0, res.Parts[1].X)
+	assert.Equal(93, res.Parts[2].X)
+
+	// the part of the second device did not take part, so its x-coordinate stays unknown
+	err = service(t, devices[0], devices[2]).AddHolders(res, []device.Recipient{devices[3]})
+
+	assert.EqualError(err, "x-coordinates of 1 parts are unknown - a new share may coincide with one of them and prevent the holders from combining together, so please combine with their devices or allow unknown x-coordinates")
+	assert.Len(res.Parts, 3)
+
+	var msgs []string
+
+	s := New(soft.NewBackend(devices[0], devices[2]), record(t, &msgs), pins())
+	s.UnknownX = true
+
+	err = s.AddHolders(res, []device.Recipient{devices[3]})
+
+	assert.NoError(err)
+	assert.Contains(msgs, "x-coordinates of 1 parts are unknown - a new share may coincide with one of them, which does not weaken the sharing, but prevents the holders from combining together")
+	assert.Equal(1, res.Parts[3].X)
+	assert.Equal(1, res.Parts[3].Index)
+
+	secret, err := service(t, devices[3], devices[1]).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+
+}
+
+func TestMigrateAndRewrap(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256(), elliptic.P256(), elliptic.P256(), elliptic.P256())
+
+	res := legacy(t, devices[:3], 200, 17, 93)
+
+	err := service(t, devices[2], devices[0]).Migrate(res)
+
+	assert.NoError(err)
+
+	// parts of version 1 results take the next free index
+	err = service(t, devices[1]).Rewrap(res, devices[1].Serial(), devices[3])
+
+	assert.NoError(err)
+	assert.Equal(devices[3].Serial(), res.Parts[1].Serial)
+	assert.Equal(1, res.Parts[1].Index)
+	assert.Equal(result.CipherChaCha20Poly1305, res.Parts[1].Algorithm.Cipher)
+
+	err = service(t, devices[2]).Rewrap(res, devices[2].Serial(), devices[4])
+
+	assert.NoError(err)
+	assert.Equal(2, res.Parts[2].Index)
+	assert.NoError(res.Validate())
+
+	secret, err := service(t, devices[3], devices[4]).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+
+	secret, err = service(t, devices[0], devices[3]).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+
+}
+
+func TestSplitToAndCombinePrime(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var (
+		curves     []elliptic.Curve
+		recipients []device.Recipient
+	)
+
+	for len(curves) < 260 {
+		curves = append(curves, elliptic.P256())
+	}
+
+	devices := devices(t, curves...)
+
+	for _, d := range devices {
+		recipients = append(recipients, d)
+	}
+
+	s := service(t)
+
+	_, err := s.SplitTo([]byte("my secret"), recipients, 3)
+
+	assert.EqualError(err, "invalid parameters: threshold 3, parts 260")
+
+	s.Prime = true
+
+	res, err := s.SplitTo([]byte("my secret"), recipients, 3)
+
+	assert.NoError(err)
+	assert.Equal(result.SharingShamirP256, res.Sharing)
+	assert.Len(res.Parts, 260)
+	assert.Equal(260, res.Parts[259].X)
+
+	secret, err := service(t, devices[259], devices[0], devices[100]).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+
+	// parts can be added beyond 255 as well
+	extra, err := soft.Generate(elliptic.P256(), 1000, soft.DefaultPIN)
+
+	assert.NoError(err)
+
+	err = service(t, devices[5], devices[6], devices[7]).AddHolders(res, []device.Recipient{extra})
+
+	assert.NoError(err)
+	assert.Equal(261, res.Parts[260].X)
+
+	secret, err = service(t, extra, devices[1], devices[2]).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+
+}
+
+func TestSplitAndCombinePolicy(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256(), elliptic.P384(), elliptic.P256(), elliptic.P256())
+
+	// 2 of 3 engineers and 1 of 2 managers
+	policy := &Policy{
+		Groups: []*Policy{
+			{Holders: []device.Recipient{devices[0], devices[1], devices[2]}, Name: "engineers", Threshold: 2},
+			{Holders: []device.Recipient{devices[3], devices[4]}, Name: "managers", Threshold: 1},
+		},
+		Name:      "policy",
+		Threshold: 2,
+	}
+
+	res, err := service(t).SplitPolicy([]byte("my secret"), policy)
+
+	assert.NoError(err)
+	assert.Len(res.Parts, 5)
+	assert.Equal(2, res.Threshold)
+	assert.Equal([]uint32{4, 5}, res.Policy.Groups[1].Serials)
+
+	var msgs []string
+
+	secret, err := New(soft.NewBackend(devices[0], devices[2], devices[4]), record(t, &msgs), pins()).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+	assert.Contains(msgs, `group "engineers" is not satisfied yet: 1 of 2 required members present`)
+	assert.Contains(msgs, `group "managers" is not satisfied yet: 0 of 1 required members present`)
+	assert.Contains(msgs, `group "policy" is not satisfied yet: 1 of 2 required members present`)
+
+	// all engineers do not satisfy the policy without a manager
+	_, err = New(soft.NewBackend(devices[0], devices[1], devices[2]), record(t, &msgs), pins()).Combine(res)
+
+	assert.Error(err)
+
+	policy.Groups[1].Holders = append(policy.Groups[1].Holders, devices[0])
+
+	_, err = service(t).SplitPolicy([]byte("my secret"), policy)
+
+	assert.EqualError(err, "duplicate device used (serial number 1)")
+
+	policy.Groups[1].Holders = policy.Groups[1].Holders[:2]
+	policy.Groups[1].Threshold = 3
+
+	_, err = service(t).SplitPolicy([]byte("my secret"), policy)
+
+	assert.EqualError(err, `invalid threshold 3 for 2 members of group "managers"`)
+
+}
+
+func TestSplitAndCombineWeighted(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256(), elliptic.P256(), elliptic.P256())
+
+	for _, to := range []bool{false, true} {
+
+		var (
+			res *result.Result
+			err error
+			s   = service(t, devices...)
+		)
+
+		// the first device counts as two holders in a 3 of 5 scheme
+		s.Weights = map[uint32]int{1: 2}
+
+		if to {
+			res, err = s.SplitTo([]byte("my secret"), []device.Recipient{devices[0], devices[1], devices[2], devices[3]}, 3)
+		} else {
+			res, err = s.Split([]byte("my secret"), 4, 3)
+		}
+
+		assert.NoError(err)
+		assert.Len(res.Parts, 4)
+		assert.Equal(2, res.Parts[0].Weight)
+		assert.Equal(3, res.Parts[1].X)
+		assert.Equal(0, res.Parts[1].Weight)
+
+		secret, err := service(t, devices[3], devices[0]).Combine(res)
+
+		assert.NoError(err)
+		assert.Equal("my secret", string(secret))
+
+		// the other holders still need three devices
+		secret, err = service(t, devices[1], devices[2], devices[3]).Combine(res)
+
+		assert.NoError(err)
+		assert.Equal("my secret", string(secret))
+
+		// holders are added after the weighted shares
+		extra, err := soft.Generate(elliptic.P256(), 5, soft.DefaultPIN)
+
+		assert.NoError(err)
+
+		err = service(t, devices[0], devices[1]).AddHolders(res, []device.Recipient{extra})
+
+		assert.NoError(err)
+		assert.Equal(6, res.Parts[4].X)
+
+	}
+
+	// the weight cannot be changed
+	res, err := service(t, devices...).SplitTo([]byte("my secret"), []device.Recipient{devices[0], devices[1], devices[2]}, 2)
+
+	assert.NoError(err)
+
+	res.Parts[0].Weight = 2
+
+	_, err = service(t, devices[0], devices[1]).Combine(res)
+
+	assert.EqualError(err, "invalid result: duplicate x-coordinate 2 in parts of devices 1 and 2")
+
+	res.Parts[0].Weight = 0
+	res.Parts[2].Weight = 3
+
+	_, err = service(t, devices[2], devices[1]).Combine(res)
+
+	assert.Error(err)
+
+	_, err = unpack(&result.Part{Serial: 1, Weight: 2}, []byte{1, 2, 3})
+
+	assert.EqualError(err, "part of device 1 does not hold 2 shares of equal length")
+
+	s := service(t)
+	s.Weights = map[uint32]int{1: 0}
+
+	_, err = s.SplitTo([]byte("my secret"), []device.Recipient{devices[0], devices[1]}, 2)
+
+	assert.EqualError(err, "invalid weight 0 for device 1")
+
+	// the shares of weighted devices are generated in advance, so the last parts are reserved for them
+	var msgs []string
+
+	s = New(soft.NewBackend(devices[0], devices[1]), record(t, &msgs), pins())
+	s.Weights = map[uint32]int{99: 2}
+
+	_, err = s.Split([]byte("my secret"), 2, 3)
+
+	assert.Error(err)
+	assert.Contains(msgs, "skipping device: device 2 has no weight, but the remaining parts are reserved for the weighted devices")
+
+	s = service(t, devices...)
+	s.Weights = map[uint32]int{devices[2].Serial(): 2}
+
+	res, err = s.Split([]byte("my secret"), 2, 3)
+
+	assert.NoError(err)
+	assert.Equal(devices[2].Serial(), res.Parts[1].Serial)
+	assert.Equal(2, res.Parts[1].Weight)
+
+	s = service(t, devices...)
+	s.Weights = map[uint32]int{98: 2, 99: 2}
+
+	_, err = s.Split([]byte("my secret"), 1, 1)
+
+	assert.EqualError(err, "2 weighted devices exceed 1 parts")
+
+}
+
+func TestSplitAndCombineBackup(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256(), elliptic.P256(), elliptic.P384(), elliptic.P256())
+
+	s := service(t)
+	s.Backups = map[uint32][]device.Recipient{
+		1: {devices[3]},
+		2: {devices[4]},
+	}
+
+	res, err := s.SplitTo([]byte("my secret"), []device.Recipient{devices[0], devices[1], devices[2]}, 2)
+
+	assert.NoError(err)
+	assert.Len(res.Parts, 3)
+	assert.Len(res.Parts[0].Backups, 1)
+	assert.Equal(uint32(4), res.Parts[0].Backups[0].Serial)
+	assert.Equal(res.Parts[0].Index, res.Parts[0].Backups[0].Index)
+
+	// the primary and backup device of a holder count as one share
+	var msgs []string
+
+	secret, err := New(soft.NewBackend(devices[3], devices[0], devices[4]), record(t, &msgs), pins()).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+	assert.Contains(msgs, "candidate 1: backup serial 4, issuer CN=yess soft device 4, subject CN=yess soft device 4, expiry "+res.Parts[0].Backups[0].Expiry)
+	assert.Contains(msgs, "skipping device: duplicate device used (serial number 1)")
+
+	// backups of splits with connected devices
+	s = service(t, devices[:3]...)
+	s.Backups = map[uint32][]device.Recipient{3: {devices[4]}}
+
+	res, err = s.Split([]byte("my secret"), 3, 2)
+
+	assert.NoError(err)
+	assert.Len(res.Parts[2].Backups, 1)
+
+	secret, err = service(t, devices[4], devices[1]).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+
+	// backup devices cannot be holders
+	s = service(t)
+	s.Backups = map[uint32][]device.Recipient{1: {devices[1]}}
+
+	_, err = s.SplitTo([]byte("my secret"), []device.Recipient{devices[0], devices[1]}, 2)
+
+	assert.EqualError(err, "duplicate device used (serial number 2)")
+
+}
+
+func TestReshare(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256(), elliptic.P256(), elliptic.P256(), elliptic.P256())
+
+	reshare := func(s *Split, res *result.Result) (*result.Result, error) {
+
+		return s.Reshare(res, func(secret []byte) (*result.Result, error) {
+			return s.SplitTo(secret, []device.Recipient{devices[2], devices[3], devices[4]}, 3)
+		})
+
+	}
+
+	for _, hybrid := range []bool{false, true} {
+
+		s := service(t, devices[:2]...)
+		s.Hybrid = hybrid
+
+		res, err := s.Split([]byte("my secret"), 2, 2)
+
+		assert.NoError(err)
+
+		next, err := reshare(service(t, devices[:2]...), res)
+
+		assert.NoError(err)
+		assert.Equal(res.ID, next.Previous)
+		assert.Equal(res.Mode, next.Mode)
+		assert.Equal(3, next.Threshold)
+		assert.Len(next.Parts, 3)
+
+		if hybrid {
+			assert.NotEqual(res.Payload, next.Payload)
+		}
+
+		// the old holders are no longer part of the new result
+		for _, d := range devices[:2] {
+			part, _ := next.Find(d.Serial())
+			assert.Nil(part)
+		}
+
+		secret, err := service(t, devices[4], devices[2], devices[3]).Combine(next)
+
+		assert.NoError(err)
+		assert.Equal("my secret", string(secret))
+
+	}
+
+	// streamed payloads are re-encrypted for the new result
+	secret := bytes.Repeat([]byte("my secret"), 20000)
+
+	var payload bytes.Buffer
+
+	s := service(t, devices[:2]...)
+	s.Stream = &Stream{In: bytes.NewReader(secret), Out: &payload}
+
+	res, err := s.Split(nil, 2, 2)
+
+	assert.NoError(err)
+
+	_, err = reshare(service(t, devices[:2]...), res)
+
+	assert.EqualError(err, "result has a streamed payload - please pass the encrypted payload")
+
+	var resharedPayload bytes.Buffer
+
+	s = service(t, devices[:2]...)
+	s.Stream = &Stream{In: bytes.NewReader(payload.Bytes()), Out: &resharedPayload}
+
+	next, err := reshare(s, res)
+
+	assert.NoError(err)
+	assert.Equal(result.ModeStream, next.Mode)
+	assert.Equal(res.ID, next.Previous)
+	assert.NotEqual(payload.Bytes(), resharedPayload.Bytes())
+
+	var out bytes.Buffer
+
+	s = service(t, devices[2:]...)
+	s.Stream = &Stream{In: bytes.NewReader(resharedPayload.Bytes()), Out: &out}
+
+	_, err = s.Combine(next)
+
+	assert.NoError(err)
+	assert.Equal(secret, out.Bytes())
+
+}
+
+func TestRewrap(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256(), elliptic.P256(), elliptic.P384(), elliptic.P256(), elliptic.P256())
+
+	s := service(t)
+	s.Backups = map[uint32][]device.Recipient{devices[0].Serial(): {devices[4]}}
+
+	res, err := s.SplitTo([]byte("my secret"), []device.Recipient{devices[0], devices[1], devices[2]}, 2)
+
+	assert.NoError(err)
+
+	err = service(t, devices[1]).Rewrap(res, 42, devices[3])
+
+	assert.EqualError(err, "result has no part for device 42")
+
+	err = service(t, devices[1]).Rewrap(res, devices[1].Serial(), devices[4])
+
+	assert.EqualError(err, fmt.Sprintf("duplicate device used (serial number %d)", devices[4].Serial()))
+
+	// the new device takes over the index and x-coordinate of the part
+	err = service(t, devices[1]).Rewrap(res, devices[1].Serial(), devices[3])
+
+	assert.NoError(err)
+	assert.Len(res.Parts, 3)
+	assert.Equal(devices[3].Serial(), res.Parts[1].Serial)
+	assert.Equal(2, res.Parts[1].Index)
+	assert.Equal(2, res.Parts[1].X)
+
+	// backup devices can be moved as well
+	err = service(t, devices[4]).Rewrap(res, devices[4].Serial(), devices[5])
+
+	assert.NoError(err)
+	assert.Equal(devices[5].Serial(), res.Parts[0].Backups[0].Serial)
+
+	secret, err := service(t, devices[3], devices[5]).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+
+	var msgs []string
+
+	_, err = New(soft.NewBackend(devices[1], devices[0], devices[2]), record(t, &msgs), pins()).Combine(res)
+
+	assert.NoError(err)
+	assert.Contains(msgs, "skipping device: invalid device added - it was not part of the original share group")
+
+	// the policy names the new device instead
+	policy := &Policy{
+		Groups: []*Policy{
+			{Holders: []device.Recipient{devices[0], devices[1]}, Name: "engineers", Threshold: 2},
+		},
+		Holders:   []device.Recipient{devices[2]},
+		Name:      "policy",
+		Threshold: 2,
+	}
+
+	res, err = service(t).SplitPolicy([]byte("my secret"), policy)
+
+	assert.NoError(err)
+
+	err = service(t, devices[1]).Rewrap(res, devices[1].Serial(), devices[3])
+
+	assert.NoError(err)
+	assert.Equal([]uint32{devices[0].Serial(), devices[3].Serial()}, res.Policy.Groups[0].Serials)
+
+	secret, err = service(t, devices[2], devices[3], devices[0]).Combine(res)
+
+	assert.NoError(err)
+	assert.Equal("my secret", string(secret))
+
+}
+
+func TestVerify(t *testing.T) {
+
+	assert := assert.New(t)
+
+	devices := devices(t, elliptic.P256(), elliptic.P256(), elliptic.P256())
+
+	s := service(t)
+	s.Hybrid = true
+	s.Weights = map[uint32]int{devices[2].Serial(): 2}
+
+	res, err := s.SplitTo([]byte("my secret"), []device.Recipient{devices[0], devices[1], devices[2]}, 3)
+
+	assert.NoError(err)
+
+	serials, err := service(t, devices[2], devices[0]).Verify(res)
+
+	assert.NoError(err)
+	assert.Equal([]uint32{devices[2].Serial(), devices[0].Serial()}, serials)
+
+	// a payload of another result is detected
+	payload := res.Payload
+	res.Payload = append([]byte{}, payload...)
+	res.Payload[0] ^= 1
+
+	_, err = service(t, devices[2], devices[0]).Verify(res)
+
+	assert.EqualError(err, "failed to decrypt payload - it has been tampered with or belongs to another result")
+
+	// streamed payloads are decrypted in full without writing the secret
+	var encrypted bytes.Buffer
+
+	s = service(t)
+	s.Stream = &Stream{In: bytes.NewReader(bytes.Repeat([]byte("my secret"), 20000)), Out: &encrypted}
+
+	res, err = s.SplitTo(nil, []device.Recipient{devices[0], devices[1]}, 2)
+
+	assert.NoError(err)
+
+	_, err = service(t, devices...).Verify(res)
+
+	assert.EqualError(err, "result has a streamed payload - please pass the encrypted payload")
+
+	s = service(t, devices[1], devices[0])
+	s.Stream = &Stream{In: bytes.NewReader(encrypted.Bytes()[:encrypted.Len()-1])}
+
+	_, err = s.Verify(res)
+
+	assert.EqualError(err, "failed to stream payload: failed to decrypt chunk 2 - the payload has been tampered with or truncated")
+
+	s = service(t, devices[1], devices[0])
+	s.Stream = &Stream{In: bytes.NewReader(encrypted.Bytes())}
+
+	serials, err = s.Verify(res)
+
+	assert.NoError(err)
+	assert.Len(serials, 2)
+
+}
@@ -0,0 +1,70 @@
+package split
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/kreuzwerker/yess/result"
+)
+
+const logVerifiedResult = "secret of result %s recovered and discarded"
+
+// Verify runs a full combination of the result, including the decryption of its payload, but discards the secret instead of returning it - it returns the serials of the holders whose shares took part in the recovery
+func (s *Split) Verify(res *result.Result) ([]uint32, error) {
+
+	if res.Mode == result.ModeDEK && len(res.Payload) == 0 {
+		return nil, fmt.Errorf(errMissingPayload)
+	}
+
+	if res.Mode == result.ModeStream {
+
+		if s.Stream == nil {
+			return nil, fmt.Errorf(errMissingStream)
+		}
+
+		// the stream is authenticated while it is read, so it is decrypted in full
+		s.Stream = &Stream{In: s.Stream.In, Out: ioutil.Discard}
+
+	}
+
+	combined, _, parts, err := s.collect(res)
+
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := s.finish(res, combined)
+
+	for _, b := range [][]byte{combined, secret} {
+
+		for i := range b {
+			b[i] = 0
+		}
+
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		seen    = make(map[uint32]bool)
+		serials []uint32
+	)
+
+	// weighted parts take part with several shares
+	for _, part := range parts {
+
+		if !seen[part.Serial] {
+			serials = append(serials, part.Serial)
+		}
+
+		seen[part.Serial] = true
+
+	}
+
+	s.out(logVerifiedResult, res.ID)
+
+	return serials, nil
+
+}
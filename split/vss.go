@@ -0,0 +1,102 @@
+package split
+
+import (
+	"fmt"
+
+	"github.com/kreuzwerker/yess/result"
+	shamir "github.com/kreuzwerker/yess/share"
+	"github.com/kreuzwerker/yess/vss"
+	"github.com/pkg/errors"
+)
+
+const (
+	errMissingCommitments = "result has no commitments - only results split with verifiable sharing can be verified"
+	logShareVerified      = "share of device %d is consistent with the commitments"
+)
+
+// VerifyShare decrypts the part of a single connected device and verifies its share against the commitments of the result, which neither requires other devices nor reveals the secret
+func (s *Split) VerifyShare(res *result.Result) (uint32, error) {
+
+	if err := res.Validate(); err != nil {
+		return 0, errors.Wrapf(err, errInvalidResult)
+	}
+
+	if res.Sharing != result.SharingFeldman {
+		return 0, fmt.Errorf(errMissingCommitments)
+	}
+
+	d, err := s.next(func(serial uint32) bool {
+		part, _ := res.Find(serial)
+		return part != nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer d.Close()
+
+	serial := d.Serial()
+	part, stanza := res.Find(serial)
+
+	if part == nil {
+		return 0, errors.New(errInvalidDevice)
+	}
+
+	plaintext, err := d.Decrypt(res, stanza)
+
+	if err != nil {
+		return serial, errors.Wrapf(err, errFailedToDecrypt, serial)
+	}
+
+	if _, err := check(res, part, plaintext); err != nil {
+		return serial, err
+	}
+
+	s.out(logShareVerified, serial)
+
+	return serial, nil
+
+}
+
+// combine combines the shares with the sharing scheme of the result
+func combine(res *result.Result, shares [][]byte) ([]byte, error) {
+
+	switch res.Sharing {
+	case result.SharingFeldman:
+		return vss.Combine(shares, res.Commitments)
+	case result.SharingShamirP256:
+		return shamir.CombinePrime(shares)
+	}
+
+	return shamir.Combine(shares)
+
+}
+
+// share splits the data with the sharing scheme of the split service and records it in the result - verifiable sharing already uses a prime field
+func (s *Split) share(res *result.Result, data []byte, parts, threshold int) ([][]byte, error) {
+
+	if s.Prime && !s.VSS {
+
+		res.Sharing = result.SharingShamirP256
+
+		return shamir.SplitPrime(data, parts, threshold)
+
+	}
+
+	if !s.VSS {
+		return shamir.Split(data, parts, threshold)
+	}
+
+	shares, commitments, err := vss.Split(data, parts, threshold)
+
+	if err != nil {
+		return nil, err
+	}
+
+	res.Commitments = commitments
+	res.Sharing = result.SharingFeldman
+
+	return shares, nil
+
+}
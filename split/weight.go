@@ -0,0 +1,95 @@
+package split
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kreuzwerker/yess/device"
+	"github.com/kreuzwerker/yess/result"
+)
+
+const (
+	errInvalidWeight  = "invalid weight %d for device %d"
+	errMalformedPart  = "part of device %d does not hold %d shares of equal length"
+	errPolicyWeights  = "weights are not supported for policies - add the holder to several groups instead"
+	logWeightedDevice = "device %d receives %d shares"
+)
+
+// count returns the number of shares required for the given number of devices - since the weighted devices are not known in advance, all of them have to take part, which Split ensures by reserving the last parts for them
+func (s *Split) count(devices int) (int, error) {
+
+	count := devices
+
+	for serial, weight := range s.Weights {
+
+		if weight < 1 {
+			return 0, fmt.Errorf(errInvalidWeight, weight, serial)
+		}
+
+		count += weight - 1
+
+	}
+
+	return count, nil
+
+}
+
+// weight returns the number of shares of the device with the given serial
+func (s *Split) weight(serial uint32) int {
+
+	if weight, ok := s.Weights[serial]; ok {
+		return weight
+	}
+
+	return 1
+
+}
+
+// assign encrypts the next shares according to the weight of the recipient into a single part
+func (s *Split) assign(res *result.Result, r device.Recipient, shares [][]byte) (*result.Part, error) {
+
+	weight := s.weight(r.Serial())
+
+	if weight > 1 {
+		s.out(logWeightedDevice, r.Serial(), weight)
+	}
+
+	plaintext := bytes.Join(shares[:weight], nil)
+
+	part, err := r.Encrypt(res, len(res.Parts)+1, plaintext)
+
+	if err != nil {
+		return nil, err
+	}
+
+	part.X = coordinate(res, shares[0])
+
+	if weight > 1 {
+		part.Weight = weight
+	}
+
+	return part, s.backup(res, part, plaintext)
+
+}
+
+// unpack splits the plaintext of a part into its shares
+func unpack(part *result.Part, plaintext []byte) ([][]byte, error) {
+
+	count := part.Shares()
+
+	if len(plaintext) == 0 || len(plaintext)%count != 0 {
+		return nil, fmt.Errorf(errMalformedPart, part.Serial, count)
+	}
+
+	var (
+		shares [][]byte
+		size   = len(plaintext) / count
+	)
+
+	for i := 0; i < len(plaintext); i += size {
+		shares = append(shares, plaintext[i:i+size])
+	}
+
+	return shares, nil
+
+}
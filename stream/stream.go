@@ -0,0 +1,216 @@
+// Package stream implements chunked authenticated encryption (STREAM) of payloads of arbitrary size with constant memory use
+package stream
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// ChunkSize is the size of the plaintext of all but the last chunk - every chunk is sealed with ChaCha20-Poly1305 using a nonce made of a chunk counter and a flag marking the last chunk, which detects reordering and truncation
+const ChunkSize = 64 * 1024
+
+const (
+	errFailedToDecryptChunk = "failed to decrypt chunk %d - the payload has been tampered with or truncated"
+	errFailedToInitialize   = "failed to initialize cipher"
+	errWriterClosed         = "write to closed stream"
+)
+
+var Debug func(string, ...interface{})
+
+// Reader decrypts a stream written by Writer
+type Reader struct {
+	aead    cipher.AEAD
+	ahead   []byte
+	buf     []byte
+	chunk   []byte
+	counter uint64
+	done    bool
+	in      io.Reader
+}
+
+// Writer encrypts everything written to it into a stream - Close must be called to write the last chunk
+type Writer struct {
+	aead    cipher.AEAD
+	buf     []byte
+	closed  bool
+	counter uint64
+	out     io.Writer
+}
+
+// NewReader returns a reader decrypting the stream from in with the given 32 byte key
+func NewReader(in io.Reader, key []byte) (*Reader, error) {
+
+	aead, err := chacha20poly1305.New(key)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToInitialize)
+	}
+
+	return &Reader{
+		aead:  aead,
+		chunk: make([]byte, ChunkSize+aead.Overhead()+1),
+		in:    in,
+	}, nil
+
+}
+
+// NewWriter returns a writer encrypting into out with the given 32 byte key, which must only be used for a single stream
+func NewWriter(out io.Writer, key []byte) (*Writer, error) {
+
+	aead, err := chacha20poly1305.New(key)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToInitialize)
+	}
+
+	return &Writer{
+		aead: aead,
+		buf:  make([]byte, 0, ChunkSize),
+		out:  out,
+	}, nil
+
+}
+
+// Read reads decrypted data - plaintext is only returned after its chunk has been authenticated
+func (r *Reader) Read(p []byte) (int, error) {
+
+	for len(r.buf) == 0 {
+
+		if r.done {
+			return 0, io.EOF
+		}
+
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+
+	return n, nil
+
+}
+
+// next reads and decrypts the next chunk - a chunk is the last one if no data follows it, which is detected by reading one byte ahead
+func (r *Reader) next() error {
+
+	var (
+		full = ChunkSize + r.aead.Overhead()
+		last bool
+		n    = copy(r.chunk, r.ahead)
+	)
+
+	m, err := io.ReadFull(r.in, r.chunk[n:full+1])
+
+	n += m
+
+	switch err {
+	case nil:
+		r.ahead = append(r.ahead[:0], r.chunk[full])
+		n = full
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+
+	out, err := r.aead.Open(r.chunk[:0], nonce(r.counter, last), r.chunk[:n], nil)
+
+	if err != nil {
+		return fmt.Errorf(errFailedToDecryptChunk, r.counter)
+	}
+
+	if Debug != nil {
+		Debug("decrypted chunk %d (%d bytes, last %t)", r.counter, len(out), last)
+	}
+
+	r.buf = out
+	r.counter++
+	r.done = last
+
+	return nil
+
+}
+
+// Close writes the last chunk
+func (w *Writer) Close() error {
+
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+
+	return w.flush(true)
+
+}
+
+// Write encrypts and writes full chunks, buffering the remainder
+func (w *Writer) Write(p []byte) (int, error) {
+
+	if w.closed {
+		return 0, fmt.Errorf(errWriterClosed)
+	}
+
+	var n int
+
+	for len(p) > 0 {
+
+		// a full buffer is only flushed once more data follows, since the last chunk must be marked
+		if len(w.buf) == ChunkSize {
+
+			if err := w.flush(false); err != nil {
+				return n, err
+			}
+
+		}
+
+		c := copy(w.buf[len(w.buf):ChunkSize], p)
+
+		w.buf = w.buf[:len(w.buf)+c]
+		p = p[c:]
+		n += c
+
+	}
+
+	return n, nil
+
+}
+
+// flush encrypts and writes the buffered chunk
+func (w *Writer) flush(last bool) error {
+
+	out := w.aead.Seal(nil, nonce(w.counter, last), w.buf, nil)
+
+	if _, err := w.out.Write(out); err != nil {
+		return err
+	}
+
+	w.buf = w.buf[:0]
+	w.counter++
+
+	return nil
+
+}
+
+// nonce returns the nonce of a chunk: the big-endian chunk counter (padded with zeros) followed by a flag byte, which is 1 for the last chunk
+func nonce(counter uint64, last bool) []byte {
+
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+
+	binary.BigEndian.PutUint64(nonce[chacha20poly1305.NonceSize-9:], counter)
+
+	if last {
+		nonce[chacha20poly1305.NonceSize-1] = 1
+	}
+
+	return nonce
+
+}
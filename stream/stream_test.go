@@ -0,0 +1,130 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encrypt(t *testing.T, key, msg []byte) []byte {
+
+	var buf bytes.Buffer
+
+	w, err := NewWriter(&buf, key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// write in odd sizes to cross chunk boundaries
+	for len(msg) > 0 {
+
+		n := 1000
+
+		if n > len(msg) {
+			n = len(msg)
+		}
+
+		if _, err := w.Write(msg[:n]); err != nil {
+			t.Fatal(err)
+		}
+
+		msg = msg[n:]
+
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+
+}
+
+func decrypt(key, in []byte) ([]byte, error) {
+
+	r, err := NewReader(bytes.NewReader(in), key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadAll(r)
+
+}
+
+func TestWriteRead(t *testing.T) {
+
+	assert := assert.New(t)
+
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	for _, size := range []int{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 3*ChunkSize + 17} {
+
+		msg := make([]byte, size)
+		rand.Read(msg)
+
+		out, err := decrypt(key, encrypt(t, key, msg))
+
+		assert.NoError(err, "size %d", size)
+		assert.Equal(len(msg), len(out), "size %d", size)
+		assert.True(bytes.Equal(msg, out), "size %d", size)
+
+	}
+
+}
+
+func TestTampering(t *testing.T) {
+
+	assert := assert.New(t)
+
+	key := make([]byte, 32)
+	rand.Read(key)
+
+	msg := make([]byte, 2*ChunkSize+100)
+	rand.Read(msg)
+
+	var (
+		in   = encrypt(t, key, msg)
+		full = ChunkSize + 16
+	)
+
+	// truncation at a chunk boundary
+	_, err := decrypt(key, in[:2*full])
+	assert.EqualError(err, "failed to decrypt chunk 1 - the payload has been tampered with or truncated")
+
+	// truncation inside a chunk
+	_, err = decrypt(key, in[:len(in)-1])
+	assert.EqualError(err, "failed to decrypt chunk 2 - the payload has been tampered with or truncated")
+
+	// truncation to nothing
+	_, err = decrypt(key, nil)
+	assert.EqualError(err, "failed to decrypt chunk 0 - the payload has been tampered with or truncated")
+
+	// appended data
+	_, err = decrypt(key, append(append([]byte{}, in...), 0))
+	assert.EqualError(err, "failed to decrypt chunk 2 - the payload has been tampered with or truncated")
+
+	// reordered chunks
+	reordered := append(append(append([]byte{}, in[full:2*full]...), in[:full]...), in[2*full:]...)
+	_, err = decrypt(key, reordered)
+	assert.EqualError(err, "failed to decrypt chunk 0 - the payload has been tampered with or truncated")
+
+	// wrong key
+	_, err = decrypt(make([]byte, 32), in)
+	assert.EqualError(err, "failed to decrypt chunk 0 - the payload has been tampered with or truncated")
+
+	// unauthenticated plaintext is never released
+	r, err := NewReader(bytes.NewReader(in[:2*full]), key)
+	assert.NoError(err)
+
+	n, err := io.Copy(ioutil.Discard, r)
+	assert.Error(err)
+	assert.Equal(int64(ChunkSize), n)
+
+}
@@ -0,0 +1,330 @@
+// Package vss implements Feldman verifiable secret sharing over the scalar field of P-256, which allows holders to verify their share against public commitments without learning the secret
+package vss
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	errDuplicateShare       = "duplicate share %d"
+	errFailedToGenerate     = "failed to generate coefficient"
+	errInvalidCommitment    = "invalid commitment %d"
+	errInvalidParameters    = "invalid parameters: threshold %d, parts %d"
+	errInvalidSecret        = "secret must be a %d byte scalar below the order of P-256"
+	errInvalidShare         = "invalid share length %d"
+	errMissingCommitments   = "no commitments"
+	errSecretNotCommitted   = "combined secret does not match the commitments - shares are missing"
+	errShareNotCommitted    = "share %d does not match the commitments"
+	errTooFewShares         = "%d shares are less than the threshold %d"
+	errUnexpectedShareIndex = "unexpected share index 0"
+)
+
+// Size is the size of secrets and of the scalar part of shares
+const Size = 32
+
+var curve = elliptic.P256()
+
+var Debug func(string, ...interface{})
+
+// Combine combines the given shares into the secret after verifying every share and the secret against the commitments
+func Combine(shares [][]byte, commitments [][]byte) ([]byte, error) {
+
+	if len(shares) < len(commitments) {
+		return nil, fmt.Errorf(errTooFewShares, len(shares), len(commitments))
+	}
+
+	xs, ys, err := points(shares, commitments)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// interpolate at zero with the first threshold shares - all shares are consistent with the commitments
+	secret := interpolate(xs[:len(commitments)], ys[:len(commitments)], new(big.Int))
+
+	if x, y := curve.ScalarBaseMult(secret.FillBytes(make([]byte, Size))); !equal(x, y, commitments[0]) {
+		return nil, fmt.Errorf(errSecretNotCommitted)
+	}
+
+	return secret.FillBytes(make([]byte, Size)), nil
+
+}
+
+// Extend returns a new share with the given index from at least threshold shares after verifying them against the commitments, which extends the existing shares by another holder without changing them
+func Extend(shares [][]byte, commitments [][]byte, index uint32) ([]byte, error) {
+
+	if len(shares) < len(commitments) {
+		return nil, fmt.Errorf(errTooFewShares, len(shares), len(commitments))
+	}
+
+	if index == 0 {
+		return nil, fmt.Errorf(errUnexpectedShareIndex)
+	}
+
+	xs, ys, err := points(shares, commitments)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, x := range xs {
+
+		if x.Cmp(big.NewInt(int64(index))) == 0 {
+			return nil, fmt.Errorf(errDuplicateShare, index)
+		}
+
+	}
+
+	share := encode(index, interpolate(xs[:len(commitments)], ys[:len(commitments)], big.NewInt(int64(index))))
+
+	if err := Verify(share, commitments); err != nil {
+		return nil, err
+	}
+
+	return share, nil
+
+}
+
+// Index returns the index (x-coordinate) of a share
+func Index(share []byte) (uint32, error) {
+
+	x, _, err := decode(share)
+
+	return x, err
+
+}
+
+// Scalar returns a random secret suitable for Split
+func Scalar() ([]byte, error) {
+
+	k, err := rand.Int(rand.Reader, curve.Params().N)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToGenerate)
+	}
+
+	return k.FillBytes(make([]byte, Size)), nil
+
+}
+
+// Split splits the secret, a scalar below the order of P-256, into parts shares with indices 1 to parts and returns the commitments to the coefficients of the polynomial as compressed points
+func Split(secret []byte, parts, threshold int) ([][]byte, [][]byte, error) {
+
+	if threshold < 2 || parts < threshold {
+		return nil, nil, fmt.Errorf(errInvalidParameters, threshold, parts)
+	}
+
+	n := curve.Params().N
+
+	if k := new(big.Int).SetBytes(secret); len(secret) != Size || k.Cmp(n) >= 0 {
+		return nil, nil, fmt.Errorf(errInvalidSecret, Size)
+	}
+
+	var (
+		coefficients = []*big.Int{new(big.Int).SetBytes(secret)}
+		commitments  [][]byte
+		shares       [][]byte
+	)
+
+	for len(coefficients) < threshold {
+
+		a, err := rand.Int(rand.Reader, n)
+
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, errFailedToGenerate)
+		}
+
+		coefficients = append(coefficients, a)
+
+	}
+
+	for _, a := range coefficients {
+		x, y := curve.ScalarBaseMult(a.FillBytes(make([]byte, Size)))
+		commitments = append(commitments, elliptic.MarshalCompressed(curve, x, y))
+	}
+
+	for i := 1; i <= parts; i++ {
+
+		x := big.NewInt(int64(i))
+
+		// evaluate with Horner's method
+		y := new(big.Int)
+
+		for j := len(coefficients) - 1; j >= 0; j-- {
+			y.Mul(y, x).Add(y, coefficients[j]).Mod(y, n)
+		}
+
+		shares = append(shares, encode(uint32(i), y))
+
+	}
+
+	if Debug != nil {
+		Debug("split secret into %d shares with a threshold of %d", parts, threshold)
+	}
+
+	return shares, commitments, nil
+
+}
+
+// Verify checks that a share lies on the polynomial committed to by the commitments
+func Verify(share []byte, commitments [][]byte) error {
+
+	if len(commitments) == 0 {
+		return fmt.Errorf(errMissingCommitments)
+	}
+
+	index, y, err := decode(share)
+
+	if err != nil {
+		return err
+	}
+
+	var (
+		n      = curve.Params().N
+		x      = big.NewInt(int64(index))
+		xj     = big.NewInt(1)
+		rx, ry *big.Int
+	)
+
+	for j, commitment := range commitments {
+
+		cx, cy := elliptic.UnmarshalCompressed(curve, commitment)
+
+		if cx == nil {
+			return fmt.Errorf(errInvalidCommitment, j)
+		}
+
+		tx, ty := curve.ScalarMult(cx, cy, xj.FillBytes(make([]byte, Size)))
+
+		if rx == nil {
+			rx, ry = tx, ty
+		} else {
+			rx, ry = curve.Add(rx, ry, tx, ty)
+		}
+
+		xj = new(big.Int).Mod(new(big.Int).Mul(xj, x), n)
+
+	}
+
+	if lx, ly := curve.ScalarBaseMult(y.FillBytes(make([]byte, Size))); lx.Cmp(rx) != 0 || ly.Cmp(ry) != 0 {
+		return fmt.Errorf(errShareNotCommitted, index)
+	}
+
+	return nil
+
+}
+
+// decode decodes a share into its index and scalar
+func decode(share []byte) (uint32, *big.Int, error) {
+
+	if len(share) != 4+Size {
+		return 0, nil, fmt.Errorf(errInvalidShare, len(share))
+	}
+
+	x := binary.BigEndian.Uint32(share)
+
+	if x == 0 {
+		return 0, nil, fmt.Errorf(errUnexpectedShareIndex)
+	}
+
+	y := new(big.Int).SetBytes(share[4:])
+
+	if y.Cmp(curve.Params().N) >= 0 {
+		return 0, nil, fmt.Errorf(errInvalidShare, len(share))
+	}
+
+	return x, y, nil
+
+}
+
+// points verifies the shares against the commitments and decodes them into distinct points
+func points(shares [][]byte, commitments [][]byte) ([]*big.Int, []*big.Int, error) {
+
+	var (
+		seen = make(map[uint32]bool)
+		xs   []*big.Int
+		ys   []*big.Int
+	)
+
+	for _, share := range shares {
+
+		if err := Verify(share, commitments); err != nil {
+			return nil, nil, err
+		}
+
+		x, y, _ := decode(share)
+
+		if seen[x] {
+			return nil, nil, fmt.Errorf(errDuplicateShare, x)
+		}
+
+		seen[x] = true
+
+		xs = append(xs, big.NewInt(int64(x)))
+		ys = append(ys, y)
+
+	}
+
+	return xs, ys, nil
+
+}
+
+// interpolate evaluates the polynomial through the given points at x with Lagrange interpolation
+func interpolate(xs, ys []*big.Int, x *big.Int) *big.Int {
+
+	var (
+		n   = curve.Params().N
+		out = new(big.Int)
+	)
+
+	for i := range xs {
+
+		num, den := big.NewInt(1), big.NewInt(1)
+
+		for j := range xs {
+
+			if i == j {
+				continue
+			}
+
+			num.Mul(num, new(big.Int).Sub(x, xs[j])).Mod(num, n)
+			den.Mul(den, new(big.Int).Sub(xs[i], xs[j])).Mod(den, n)
+
+		}
+
+		term := new(big.Int).Mul(ys[i], num)
+		term.Mul(term, new(big.Int).ModInverse(den, n))
+
+		out.Add(out, term).Mod(out, n)
+
+	}
+
+	return out
+
+}
+
+// encode encodes a share as big-endian index followed by the fixed-length scalar
+func encode(x uint32, y *big.Int) []byte {
+
+	share := make([]byte, 4, 4+Size)
+
+	binary.BigEndian.PutUint32(share, x)
+
+	return append(share, y.FillBytes(make([]byte, Size))...)
+
+}
+
+// equal returns true if the point equals the compressed point
+func equal(x, y *big.Int, compressed []byte) bool {
+
+	cx, cy := elliptic.UnmarshalCompressed(curve, compressed)
+
+	return cx != nil && cx.Cmp(x) == 0 && cy.Cmp(y) == 0
+
+}
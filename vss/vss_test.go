@@ -0,0 +1,80 @@
+package vss
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitAndCombine(t *testing.T) {
+
+	assert := assert.New(t)
+
+	secret, err := Scalar()
+
+	assert.NoError(err)
+
+	shares, commitments, err := Split(secret, 5, 3)
+
+	assert.NoError(err)
+	assert.Len(shares, 5)
+	assert.Len(commitments, 3)
+
+	for idx, share := range shares {
+
+		assert.NoError(Verify(share, commitments))
+
+		index, err := Index(share)
+
+		assert.NoError(err)
+		assert.Equal(uint32(idx+1), index)
+
+	}
+
+	res, err := Combine([][]byte{shares[4], shares[1], shares[2]}, commitments)
+
+	assert.NoError(err)
+	assert.Equal(secret, res)
+
+	_, err = Combine(shares[:2], commitments)
+
+	assert.EqualError(err, "2 shares are less than the threshold 3")
+
+	_, err = Combine([][]byte{shares[0], shares[1], shares[1]}, commitments)
+
+	assert.EqualError(err, "duplicate share 2")
+
+	// shares can be extended
+	share, err := Extend(shares[2:], commitments, 6)
+
+	assert.NoError(err)
+	assert.NoError(Verify(share, commitments))
+
+	res, err = Combine([][]byte{share, shares[0], shares[1]}, commitments)
+
+	assert.NoError(err)
+	assert.Equal(secret, res)
+
+	_, err = Extend(shares[2:], commitments, 5)
+
+	assert.EqualError(err, "duplicate share 5")
+
+	// inconsistent shares are detected
+	shares[3][Size] ^= 1
+
+	assert.EqualError(Verify(shares[3], commitments), "share 4 does not match the commitments")
+
+	_, err = Combine([][]byte{shares[3], shares[1], shares[2]}, commitments)
+
+	assert.EqualError(err, "share 4 does not match the commitments")
+
+	// the secret must be a scalar
+	_, _, err = Split([]byte("my secret"), 5, 3)
+
+	assert.EqualError(err, "secret must be a 32 byte scalar below the order of P-256")
+
+	_, _, err = Split(secret, 2, 3)
+
+	assert.EqualError(err, "invalid parameters: threshold 3, parts 2")
+
+}
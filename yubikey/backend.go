@@ -0,0 +1,130 @@
+package yubikey
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kreuzwerker/yess/device"
+	"github.com/pkg/errors"
+	"pault.ag/go/ykpiv"
+)
+
+const (
+	errFailedToListReaders = "failed to list PC/SC readers"
+	errNoMatchingDevice    = "no matching device found"
+	errSerialMismatch      = "expected device %d in reader %q, found device %d"
+)
+
+// Backend connects to Yubikeys and other PIV compatible smartcards through PC/SC
+type Backend struct {
+	Reader string // Reader restricts devices to readers whose name contains this string
+	Serial uint32 // Serial restricts devices to this serial number, if not zero
+	Slot   string // Slot is the hex name of the slot used for encryption, defaulting to device.DefaultSlot
+}
+
+// Connect connects to the listed device and logs in with the given PIN
+func (b *Backend) Connect(info *device.Info, pin string) (device.Device, error) {
+
+	yubikey, err := New(info.Reader, pin, b.Slot)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if yubikey.Serial() != info.Serial {
+		yubikey.Close()
+		return nil, fmt.Errorf(errSerialMismatch, info.Serial, info.Reader, yubikey.Serial())
+	}
+
+	return yubikey, nil
+
+}
+
+// Devices lists the devices in all readers matching the reader restriction
+func (b *Backend) Devices() ([]*device.Info, error) {
+
+	readers, err := ykpiv.Readers()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToListReaders)
+	}
+
+	var infos []*device.Info
+
+	for _, reader := range readers {
+
+		if !strings.Contains(reader, b.Reader) {
+			continue
+		}
+
+		infos = append(infos, b.probe(reader))
+
+	}
+
+	return infos, nil
+
+}
+
+// Open connects to the first device matching the reader and serial restrictions with the given PIN
+func (b *Backend) Open(pin string) (device.Device, error) {
+
+	infos, err := b.Devices()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range infos {
+
+		if info.Err != nil || (b.Serial != 0 && info.Serial != b.Serial) {
+			continue
+		}
+
+		return b.Connect(info, pin)
+
+	}
+
+	return nil, fmt.Errorf(errNoMatchingDevice)
+
+}
+
+// probe connects to the device in the given reader without logging in
+func (b *Backend) probe(reader string) *device.Info {
+
+	info := &device.Info{
+		Reader: reader,
+	}
+
+	piv, err := ykpiv.New(ykpiv.Options{
+		Reader: reader,
+	})
+
+	if err != nil {
+		info.Err = errors.Wrapf(err, errFailedToInitializeYubikey)
+		return info
+	}
+
+	yubikey := &Yubikey{
+		device: piv,
+		reader: reader,
+		slot:   b.Slot,
+		slots:  make(map[string]*ykpiv.Slot),
+	}
+
+	defer yubikey.Close()
+
+	if yubikey.slot == "" {
+		yubikey.slot = device.DefaultSlot
+	}
+
+	if info.Serial, err = yubikey.readSerial(); err != nil {
+		info.Err = err
+		return info
+	}
+
+	// the version is purely informational
+	info.Version, _ = yubikey.Version()
+
+	return info
+
+}
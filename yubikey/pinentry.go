@@ -10,10 +10,8 @@ type file interface {
 
 const defaultPIN = "123456"
 
-// PIN calls the msg function and provides PIN entry over the keyboard
-func PIN(msg func(), in file) (string, error) {
-
-	msg()
+// PIN provides PIN entry over the keyboard
+func PIN(in file) (string, error) {
 
 	pin, err := terminal.ReadPassword(int(in.Fd()))
 
@@ -0,0 +1,123 @@
+package yubikey
+
+/*
+#cgo darwin LDFLAGS: -L /usr/local/lib -lykpiv
+#cgo darwin CFLAGS: -I/usr/local/include/ykpiv/
+#cgo linux LDFLAGS: -lykpiv
+#cgo linux CFLAGS: -I/usr/include/ykpiv/
+#include <ykpiv.h>
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"fmt"
+	"io"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	algorithmX25519        = 0xe1
+	insGeneralAuthenticate = 0x87
+	swSuccess              = 0x9000
+	tagAuthentication      = 0x7c
+	tagExponentiation      = 0x85
+	tagResponse            = 0x82
+)
+
+const (
+	errFailedToAgree       = "failed to perform X25519 key agreement on device (%s)"
+	errInvalidAgreement    = "invalid response of %d bytes to X25519 key agreement"
+	errInvalidPeerKey      = "invalid X25519 public key of %d bytes"
+	errUnexpectedAgreement = "device refused X25519 key agreement with status %04x"
+)
+
+// agreement performs X25519 key agreements with the key of a slot - since the PIV library only supports RSA and ECC keys, the GENERAL AUTHENTICATE command is sent to the device in a session of its own
+type agreement struct {
+	key    byte
+	pin    string
+	public *ecdh.PublicKey
+	reader string
+}
+
+// Public returns the public key of the slot
+func (a *agreement) Public() crypto.PublicKey {
+	return a.public
+}
+
+// Decrypt performs the key agreement with the given raw public key, yielding the shared secret
+func (a *agreement) Decrypt(_ io.Reader, msg []byte, _ crypto.DecrypterOpts) ([]byte, error) {
+
+	if len(msg) != 32 {
+		return nil, fmt.Errorf(errInvalidPeerKey, len(msg))
+	}
+
+	var state *C.ykpiv_state
+
+	if rc := C.ykpiv_init(&state, 0); rc != C.YKPIV_OK {
+		return nil, failed("init", rc)
+	}
+
+	defer C.ykpiv_done(state)
+
+	reader := C.CString(a.reader)
+	defer C.free(unsafe.Pointer(reader))
+
+	if rc := C.ykpiv_connect(state, reader); rc != C.YKPIV_OK {
+		return nil, failed("connect", rc)
+	}
+
+	defer C.ykpiv_disconnect(state)
+
+	pin := C.CString(a.pin)
+	defer C.free(unsafe.Pointer(pin))
+
+	var tries C.int
+
+	if rc := C.ykpiv_verify(state, pin, &tries); rc != C.YKPIV_OK {
+		return nil, failed("verify", rc)
+	}
+
+	// the dynamic authentication template requests the response for the exponentiation with the given key
+	template := []byte{0, insGeneralAuthenticate, algorithmX25519, a.key}
+	data := append([]byte{tagAuthentication, byte(len(msg) + 4), tagResponse, 0, tagExponentiation, byte(len(msg))}, msg...)
+
+	var (
+		out    = make([]byte, 256)
+		outLen = C.ulong(len(out))
+		sw     C.int
+	)
+
+	if rc := C.ykpiv_transfer_data(
+		state,
+		(*C.uchar)(unsafe.Pointer(&template[0])),
+		(*C.uchar)(unsafe.Pointer(&data[0])), C.long(len(data)),
+		(*C.uchar)(unsafe.Pointer(&out[0])), &outLen,
+		&sw,
+	); rc != C.YKPIV_OK {
+		return nil, failed("transfer_data", rc)
+	}
+
+	if sw != swSuccess {
+		return nil, fmt.Errorf(errUnexpectedAgreement, int(sw))
+	}
+
+	out = out[:outLen]
+
+	// the shared secret is returned in the response tag of the template
+	if len(out) != len(msg)+4 || out[0] != tagAuthentication || int(out[1]) != len(msg)+2 || out[2] != tagResponse || int(out[3]) != len(msg) {
+		return nil, fmt.Errorf(errInvalidAgreement, len(out))
+	}
+
+	return append([]byte(nil), out[4:]...), nil
+
+}
+
+// failed returns the error of a failed call into the PIV library
+func failed(name string, rc C.ykpiv_rc) error {
+	return errors.Wrapf(errors.New(C.GoString(C.ykpiv_strerror(rc))), errFailedToAgree, name)
+}
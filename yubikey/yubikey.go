@@ -1,47 +1,51 @@
 package yubikey
 
 import (
-	"crypto/ecdsa"
-	"crypto/elliptic"
-	"crypto/rand"
+	"bytes"
+	"crypto/ecdh"
+	"crypto/x509"
 	"fmt"
-	"math/big"
-	"time"
 
-	"github.com/kreuzwerker/yess/encrypt"
+	"github.com/kreuzwerker/yess/device"
 	"github.com/kreuzwerker/yess/result"
 	"github.com/pkg/errors"
 	"pault.ag/go/ykpiv"
 )
 
 const (
-	errFailedToDecryptOnDevice             = "failed to decrypt on device"
-	errFailedToDecryptShare                = "failed to decrypt share"
-	errFailedToGenerateEphemeralECCKeypair = "failed to generate ephemeral keypair"
-	errFailedToGetKeyManagement            = "failed to get key management PIV slot - maybe no certificate is present"
-	errFailedToGetSerial                   = "failed to get serial from device"
-	errFailedToInitializeYubikey           = "failed to initialize Yubikey"
-	errFailedToLogin                       = "failed to log into Yubikey (%d retries remaining)"
-	errUnknownPublicKeyType                = "unknown public key type %v"
+	errFailedToGetSlot           = "failed to get PIV slot %s - maybe no certificate is present"
+	errFailedToGetSerial         = "failed to get serial from device"
+	errFailedToGetVersion        = "failed to get firmware version from device"
+	errFailedToInitializeYubikey = "failed to initialize device"
+	errFailedToLogin             = "failed to log into device (%d retries remaining)"
+	errFailedToRestoreLogin      = "failed to log into device again after X25519 key agreement"
 )
 
-// Yubikey represents a Yubikey in PIV mode
+// Yubikey represents a Yubikey (or another PIV compatible smartcard) in PIV mode
 type Yubikey struct {
-	device  *ykpiv.Yubikey
-	Expiry  string
-	Issuer  string
-	Serial  uint32
-	slot    *ykpiv.Slot
-	Subject string
+	device *ykpiv.Yubikey
+	pin    string
+	reader string
+	serial uint32
+	slot   string
+	slots  map[string]*ykpiv.Slot
 }
 
 var Debug func(string, ...interface{})
 
-// New will initialize a PIV client for a Yubikey with the given PIN
-func New(pin string) (*Yubikey, error) {
+// New will initialize a PIV client for the device in the given reader with the given PIN, selecting the given slot for encryption
+func New(reader, pin, slot string) (*Yubikey, error) {
+
+	if slot == "" {
+		slot = device.DefaultSlot
+	}
+
+	if _, err := device.ParseSlot(slot); err != nil {
+		return nil, err
+	}
 
 	piv, err := ykpiv.New(ykpiv.Options{
-		Reader: "Yubico YubiKey",
+		Reader: reader,
 		PIN:    &pin,
 	})
 
@@ -49,41 +53,55 @@ func New(pin string) (*Yubikey, error) {
 		return nil, errors.Wrapf(err, errFailedToInitializeYubikey)
 	}
 
-	serial, err := piv.Serial()
-
-	if err != nil {
-		return nil, errors.Wrapf(err, errFailedToGetSerial)
-	}
-
 	yubikey := &Yubikey{
 		device: piv,
-		Serial: serial,
+		pin:    pin,
+		reader: reader,
+		slot:   slot,
+		slots:  make(map[string]*ykpiv.Slot),
+	}
+
+	if yubikey.serial, err = yubikey.readSerial(); err != nil {
+		piv.Close()
+		return nil, err
 	}
 
 	if Debug != nil {
-		Debug("connecting to Yubikey %d with PIN %q", serial, pin)
+		Debug("connecting to device %d in reader %q with PIN %q", yubikey.serial, reader, pin)
 	}
 
 	if err := piv.Login(); err != nil {
+
 		retries, _ := piv.PINRetries()
+		piv.Close()
+
+		if ykpiv.WrongPIN.Equal(err) || ykpiv.PINLockedError.Equal(err) {
+
+			return nil, &device.PINError{
+				Retries: retries,
+				Serial:  yubikey.serial,
+			}
+
+		}
+
 		return nil, errors.Wrapf(err, errFailedToLogin, retries)
+
 	}
 
-	slot, err := piv.KeyManagement()
+	return yubikey, nil
 
-	if err != nil {
-		return nil, errors.Wrapf(err, errFailedToGetKeyManagement)
-	}
+}
 
-	yubikey.slot = slot
+// Certificate returns the certificate of the selected slot or nil, if the slot holds no certificate
+func (y *Yubikey) Certificate() *x509.Certificate {
 
-	cert := slot.Certificate
+	slot, err := y.load(y.slot)
 
-	yubikey.Expiry = cert.NotAfter.Format(time.RFC3339)
-	yubikey.Issuer = cert.Issuer.String()
-	yubikey.Subject = cert.Subject.String()
+	if err != nil {
+		return nil
+	}
 
-	return yubikey, nil
+	return slot.Certificate
 
 }
 
@@ -92,125 +110,184 @@ func (y *Yubikey) Close() error {
 	return y.device.Close()
 }
 
-// Decrypt decrypts a given part, yielding the plaintext share
-func (y *Yubikey) Decrypt(p *result.Part) ([]byte, error) {
+// Decrypt decrypts a given part with the slot recorded in the part, yielding the plaintext share. If that fails, the retired key management slots are tried, since keys are commonly moved there after rotation.
+func (y *Yubikey) Decrypt(res *result.Result, p *result.Part) ([]byte, error) {
 
-	pk, err := p.Key()
+	share, err := y.decrypt(p.Slot, res, p)
 
-	if err != nil {
-		return nil, err
+	if err == nil {
+		return share, nil
 	}
 
-	switch t := pk.(type) {
-	case *ecdsa.PublicKey:
-		return y.decryptECC(t, p)
-	default:
-		return nil, fmt.Errorf(errUnknownPublicKeyType, t)
-	}
+	for key := 0x82; key <= 0x95; key++ {
 
-}
+		name := fmt.Sprintf("%x", key)
 
-// decryptECC decrypts a given part using ECC keys, yielding the plaintext share
-func (y *Yubikey) decryptECC(ekp *ecdsa.PublicKey, p *result.Part) ([]byte, error) {
+		if name == p.Slot {
+			continue
+		}
 
-	// marshal the public key into the expected ANSI X9.62 format - see https://pkg.go.dev/pault.ag/go/ykpiv?tab=doc#Slot.Decrypt
-	octet := elliptic.Marshal(ekp.Curve, ekp.X, ekp.Y)
+		if share, err := y.decrypt(name, res, p); err == nil {
 
-	// decrypt, yielding the shared ephemeral key
-	sk, err := y.slot.Decrypt(nil, octet, nil)
+			if Debug != nil {
+				Debug("decrypted part of slot %q with retired slot %s", p.Slot, name)
+			}
 
-	if err != nil {
-		return nil, errors.Wrapf(err, errFailedToDecryptOnDevice)
-	}
+			return share, nil
+
+		}
 
-	if Debug != nil {
-		Debug("decrypting with ECC using SK %x", sk)
 	}
 
-	// decrypt the ciphertext share with the shared ephemeral key
-	share, ok := encrypt.Decrypt(sk, p.Share)
+	return nil, err
 
-	if !ok {
-		return nil, fmt.Errorf(errFailedToDecryptShare)
-	}
+}
 
-	if Debug != nil {
-		Debug("decrypted share %x", share)
+// Encrypt encrypts the given share of a result with the selected slot into the part with the given index
+func (y *Yubikey) Encrypt(res *result.Result, index int, msg []byte) (*result.Part, error) {
+
+	if _, err := y.load(y.slot); err != nil {
+		return nil, err
 	}
 
-	return share, nil
+	return device.Encrypt(y, res, index, msg)
 
 }
 
-// Encrypt encrypts the given share into a Result
-func (y *Yubikey) Encrypt(msg []byte) (*result.Part, error) {
+// Serial returns the serial number of the Yubikey
+func (y *Yubikey) Serial() uint32 {
+	return y.serial
+}
 
-	if Debug != nil {
-		Debug("encrypting plaintext share %x", msg)
+// Slot returns the hex name of the selected slot
+func (y *Yubikey) Slot() string {
+	return y.slot
+}
+
+// Version returns the firmware version of the Yubikey
+func (y *Yubikey) Version() (string, error) {
+
+	version, err := y.device.Version()
+
+	if err != nil {
+		return "", errors.Wrapf(err, errFailedToGetVersion)
 	}
 
-	switch t := y.slot.Public().(type) {
-	case *ecdsa.PublicKey:
-		return y.encryptECC(t, msg)
-	default:
-		return nil, fmt.Errorf(errUnknownPublicKeyType, t)
+	// the version is returned as a NUL terminated C string
+	if idx := bytes.IndexByte(version, 0); idx >= 0 {
+		version = version[:idx]
 	}
 
+	return string(version), nil
+
 }
 
-// encryptECC encrypts the given share using ECC keys into a Result
-func (y *Yubikey) encryptECC(dkp *ecdsa.PublicKey, msg []byte) (*result.Part, error) {
+// Vendor returns the name of the reader of the device, which contains its vendor string
+func (y *Yubikey) Vendor() string {
+	return y.reader
+}
 
-	var (
-		ekp *ecdsa.PublicKey
-		sk  *big.Int
-	)
+// decrypt decrypts a given part with the given slot
+func (y *Yubikey) decrypt(name string, res *result.Result, p *result.Part) ([]byte, error) {
 
-	{
+	slot, err := y.load(name)
 
-		// generate ephemeral keypair
-		eks, px, py, err := elliptic.GenerateKey(dkp.Curve, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
 
-		if err != nil {
-			return nil, errors.Wrapf(err, errFailedToGenerateEphemeralECCKeypair)
-		}
+	pk, err := device.PublicKey(slot.Certificate)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if pk, ok := pk.(*ecdh.PublicKey); ok {
+
+		// load validated the name already
+		key, _ := device.ParseSlot(name)
+
+		share, err := device.Decrypt(&agreement{
+			key:    key,
+			pin:    y.pin,
+			public: pk,
+			reader: y.reader,
+		}, res, p)
 
-		// the ecsda structure is used due to native marshalling capabilities in package X509
-		ekp = &ecdsa.PublicKey{
-			X:     px,
-			Y:     py,
-			Curve: dkp.Curve,
+		// the session of the key agreement selects the PIV application again, which resets the verified PIN
+		if err := y.device.Login(); err != nil {
+			return nil, errors.Wrapf(err, errFailedToRestoreLogin)
 		}
 
-		// perform key exchange
-		sk, _ = dkp.Curve.ScalarMult(dkp.X, dkp.Y, eks)
+		return share, err
 
 	}
 
-	if Debug != nil {
-		Debug("encrypting with ECC using SK %x", sk)
+	return device.Decrypt(slot, res, p)
+
+}
+
+// readSerial reads the serial of the device - since reading it is specific to Yubikeys, a serial derived from the public key of the selected slot is used for other devices
+func (y *Yubikey) readSerial() (uint32, error) {
+
+	serial, err := y.device.Serial()
+
+	if err == nil {
+		return serial, nil
 	}
 
-	// encrypt the plaintext share with the shared ephemeral key
-	share := encrypt.Encrypt(sk.Bytes(), msg)
+	slot, err2 := y.load(y.slot)
+
+	if err2 != nil {
+		return 0, errors.Wrapf(err, errFailedToGetSerial)
+	}
 
 	if Debug != nil {
-		Debug("encrypted share %x", share)
+		Debug("device in reader %q reports no serial (%s), deriving it from the public key of slot %s", y.reader, err, y.slot)
 	}
 
-	result := &result.Part{
-		Device:  "Yubikey", // TODO: get this from device
-		Expiry:  y.Expiry,
-		Issuer:  y.Issuer,
-		Serial:  y.Serial,
-		Share:   share,
-		Subject: y.Subject,
+	return device.DeriveSerial(slot.Certificate.RawSubjectPublicKeyInfo), nil
+
+}
+
+// load returns the slot with the given hex name, reading its certificate on first use
+func (y *Yubikey) load(name string) (*ykpiv.Slot, error) {
+
+	if name == "" {
+		name = device.DefaultSlot
+	}
+
+	if slot, ok := y.slots[name]; ok {
+		return slot, nil
 	}
 
-	if err := result.AddKey(ekp); err != nil {
+	key, err := device.ParseSlot(name)
+
+	if err != nil {
 		return nil, err
 	}
 
-	return result, nil
+	id := ykpiv.KeyManagement
+
+	// the certificates of the retired key management slots are stored in consecutive objects
+	if key != 0x9d {
+
+		id = ykpiv.SlotId{
+			Certificate: 0x5fc10d + int32(key-0x82),
+			Key:         int32(key),
+			Name:        fmt.Sprintf("Retired Key Management %d", key-0x81),
+		}
+
+	}
+
+	slot, err := y.device.Slot(id)
+
+	if err != nil {
+		return nil, errors.Wrapf(err, errFailedToGetSlot, name)
+	}
+
+	y.slots[name] = slot
+
+	return slot, nil
 
 }